@@ -1,78 +1,94 @@
 package constant
 
-var PointMap = map[uint8]uint8{
-	1: 1,
-	2: 2,
-	3: 3,
-	4: 4,
-	5: 5,
+import (
+	"sort"
+	"sync"
+)
+
+// RouteInfo 路线信息，Points 为点位编号到点位名称的映射（0 为起点，最大编号为终点），
+// Sites 为点位编号到实际地点的映射，不同路线的点位对应同一地点即为共用点位
+type RouteInfo struct {
+	ID     uint8
+	Code   string
+	Name   string
+	Campus uint8
+	Points map[int8]string
+	Sites  map[int8]uint
 }
 
-var RouteMap = map[uint8]string{
-	1: "朝晖",
-	2: "屏峰半程",
-	3: "屏峰全程",
-	4: "莫干山半程",
-	5: "莫干山全程",
+// 路线和点位数据在启动时从数据库加载，管理员修改后会重新加载
+var (
+	routeLock sync.RWMutex
+	routes    = make(map[uint8]RouteInfo)
+)
+
+// SetRoutes 替换当前全部的路线和点位数据
+func SetRoutes(list []RouteInfo) {
+	routeMap := make(map[uint8]RouteInfo, len(list))
+	for _, route := range list {
+		routeMap[route.ID] = route
+	}
+
+	routeLock.Lock()
+	routes = routeMap
+	routeLock.Unlock()
 }
 
-var ZHMap = map[int8]string{
-	0: "起点",
-	1: "上塘映翠",
-	2: "京杭大运河",
-	3: "西湖文化广场",
-	4: "中国海事",
-	5: "忠亭",
-	6: "德胜运河驿站",
-	7: "终点",
+// GetRoutes 按路线编号顺序返回全部路线
+func GetRoutes() []RouteInfo {
+	routeLock.RLock()
+	defer routeLock.RUnlock()
+
+	list := make([]RouteInfo, 0, len(routes))
+	for _, route := range routes {
+		list = append(list, route)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
 }
 
-var PFHalfMap = map[int8]string{
-	0: "起点",
-	1: "金莲寺",
-	2: "老焦山",
-	3: "屏峰山",
-	4: "屏峰善院",
-	5: "终点",
+// GetRoute 根据路线编号获取路线信息
+func GetRoute(route uint8) (RouteInfo, bool) {
+	routeLock.RLock()
+	defer routeLock.RUnlock()
+
+	info, ok := routes[route]
+	return info, ok
 }
 
-var PFAllMap = map[int8]string{
-	0: "起点",
-	1: "金莲寺",
-	2: "龙门坎",
-	3: "慈母桥",
-	4: "古樟树公园",
-	5: "屏峰山",
-	6: "屏峰善院",
-	7: "终点",
+// RouteExists 判断路线是否存在
+func RouteExists(route uint8) bool {
+	_, ok := GetRoute(route)
+	return ok
 }
-var MgsHalfMap = map[int8]string{
-	0: "起点",
-	1: "终点",
+
+// GetRouteName 返回路线名称
+func GetRouteName(route uint8) string {
+	info, ok := GetRoute(route)
+	if !ok {
+		return "未知路线"
+	}
+	return info.Name
 }
 
-var MgsAllMap = map[int8]string{
-	0: "起点",
-	1: "杨家山",
-	2: "丁家桥",
-	3: "兆丰公园",
-	4: "终点",
+// GetPointNum 返回路线终点的点位编号
+func GetPointNum(route uint8) uint8 {
+	info, _ := GetRoute(route)
+
+	var num int8
+	for point := range info.Points {
+		if point > num {
+			num = point
+		}
+	}
+	return uint8(num)
 }
 
 // GetPointName 用于根据 Route 和 Point 返回对应的点位名称
 func GetPointName(route uint8, point int8) string {
-	switch route {
-	case 1:
-		return ZHMap[point]
-	case 2:
-		return PFHalfMap[point]
-	case 3:
-		return PFAllMap[point]
-	case 4:
-		return MgsHalfMap[point]
-	case 5:
-		return MgsAllMap[point]
-	default:
+	info, ok := GetRoute(route)
+	if !ok {
 		return "未知点位"
 	}
+	return info.Points[point]
 }
@@ -0,0 +1,211 @@
+package admin
+
+import (
+	"errors"
+	"log"
+	"walk-server/middleware"
+	"walk-server/model"
+	"walk-server/service/adminService"
+	"walk-server/service/sessionService"
+	"walk-server/utility"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ChangePasswordForm struct {
+	OldPassword string `json:"old_password" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=8,max=64"`
+}
+
+// ChangePassword 管理员修改自己的密码
+func ChangePassword(c *gin.Context) {
+	var postForm ChangePasswordForm
+	if err := c.ShouldBindJSON(&postForm); err != nil {
+		utility.ResponseError(c, "参数错误，新密码至少 8 位")
+		return
+	}
+
+	user, _ := adminService.GetAdminByJWT(c)
+	if !utility.CheckPassword(user.Password, postForm.OldPassword) {
+		utility.ResponseError(c, "原密码错误")
+		return
+	}
+	if postForm.OldPassword == postForm.NewPassword {
+		utility.ResponseError(c, "新密码不能与原密码相同")
+		return
+	}
+
+	hash, err := utility.HashPassword(postForm.NewPassword)
+	if err != nil {
+		utility.ResponseError(c, "服务错误")
+		return
+	}
+	if err := adminService.UpdatePassword(user.ID, hash, false); err != nil {
+		utility.ResponseError(c, "服务错误")
+		return
+	}
+	// 修改密码后其他设备上的登录失效
+	_ = sessionService.RevokeAll(utility.KindAdmin, sessionService.AdminSubject(user.ID), utility.GetJwtData(c).Session)
+	utility.ResponseSuccess(c, nil)
+}
+
+type AdminIDForm struct {
+	AdminID uint `json:"admin_id" binding:"required"`
+}
+
+// getManagedAdmin 获取当前管理员有权管理的账号，路线负责人只能管理本路线的志愿者和观察员
+func getManagedAdmin(c *gin.Context, id uint) (*model.Admin, bool) {
+	user, _ := adminService.GetAdminByJWT(c)
+	target, err := adminService.GetAdminByID(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		utility.ResponseError(c, "管理员不存在")
+		return nil, false
+	} else if err != nil {
+		utility.ResponseError(c, "服务错误")
+		return nil, false
+	}
+
+	if target.ID == user.ID {
+		utility.ResponseError(c, "请使用修改密码功能")
+		return nil, false
+	}
+	if user.Role != model.RoleSuper &&
+		(!target.HasRole(model.RoleVolunteer, model.RoleObserver) || !middleware.CheckRouteID(user, target.Route)) {
+		utility.ResponseError(c, "没有权限")
+		return nil, false
+	}
+	return target, true
+}
+
+// resetPasswords 为管理员生成新的随机密码并撤销已有的登录，下次登录时需要修改，新密码只通过一次性凭证查看
+// 先签发凭证再在一个事务中保存密码，任何一步失败都不会留下没有人知道的密码
+func resetPasswords(admins []model.Admin) ([]gin.H, error) {
+	passwords := make([]string, 0, len(admins))
+	hashes := make(map[uint]string, len(admins))
+	for _, admin := range admins {
+		pwd, err := generateRandomPassword()
+		if err != nil {
+			return nil, err
+		}
+		hash, err := utility.HashPassword(pwd)
+		if err != nil {
+			return nil, err
+		}
+		passwords = append(passwords, pwd)
+		hashes[admin.ID] = hash
+	}
+
+	credentials, err := issueCredentials(admins, passwords)
+	if err != nil {
+		return nil, err
+	}
+	if err := adminService.UpdatePasswords(hashes, true); err != nil {
+		discardCredentials(credentials)
+		return nil, err
+	}
+
+	// 密码已经修改，撤销登录失败时仍然返回凭证
+	for _, admin := range admins {
+		if err := sessionService.RevokeAll(utility.KindAdmin, sessionService.AdminSubject(admin.ID), ""); err != nil {
+			log.Println("撤销管理员登录失败: ", err)
+		}
+	}
+	return credentials, nil
+}
+
+// ResetPassword 重置管理员密码
+func ResetPassword(c *gin.Context) {
+	var postForm AdminIDForm
+	if err := c.ShouldBindJSON(&postForm); err != nil {
+		utility.ResponseError(c, "参数错误")
+		return
+	}
+
+	target, ok := getManagedAdmin(c, postForm.AdminID)
+	if !ok {
+		return
+	}
+
+	credentials, err := resetPasswords([]model.Admin{*target})
+	if err != nil {
+		utility.ResponseError(c, "服务错误")
+		return
+	}
+	utility.ResponseSuccess(c, gin.H{
+		"admin": credentials[0],
+	})
+}
+
+type RotatePasswordsForm struct {
+	Route uint8 `json:"route" binding:"required"`
+}
+
+// RotatePasswords 批量轮换一条路线上全部志愿者的密码
+func RotatePasswords(c *gin.Context) {
+	var postForm RotatePasswordsForm
+	if err := c.ShouldBindJSON(&postForm); err != nil {
+		utility.ResponseError(c, "参数错误")
+		return
+	}
+
+	admins, err := adminService.GetAdminsByRoute(postForm.Route, model.RoleVolunteer)
+	if err != nil {
+		utility.ResponseError(c, "服务错误")
+		return
+	}
+
+	credentials, err := resetPasswords(admins)
+	if err != nil {
+		utility.ResponseError(c, "服务错误")
+		return
+	}
+	utility.ResponseSuccess(c, gin.H{
+		"admins": credentials,
+	})
+}
+
+// ForcePasswordChange 要求管理员下次登录时修改密码
+func ForcePasswordChange(c *gin.Context) {
+	var postForm AdminIDForm
+	if err := c.ShouldBindJSON(&postForm); err != nil {
+		utility.ResponseError(c, "参数错误")
+		return
+	}
+
+	target, ok := getManagedAdmin(c, postForm.AdminID)
+	if !ok {
+		return
+	}
+
+	if err := adminService.ForcePasswordChange(target.ID); err != nil {
+		utility.ResponseError(c, "服务错误")
+		return
+	}
+	utility.ResponseSuccess(c, nil)
+}
+
+type GetCredentialForm struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// GetCredential 通过一次性凭证查看新生成的账号密码，查看后凭证失效
+func GetCredential(c *gin.Context) {
+	var postForm GetCredentialForm
+	if err := c.ShouldBindJSON(&postForm); err != nil {
+		utility.ResponseError(c, "参数错误")
+		return
+	}
+
+	credential, err := adminService.TakeCredential(postForm.Token)
+	if errors.Is(err, adminService.ErrCredentialNotFound) {
+		utility.ResponseError(c, err.Error())
+		return
+	} else if err != nil {
+		utility.ResponseError(c, "服务错误")
+		return
+	}
+	utility.ResponseSuccess(c, gin.H{
+		"credential": credential,
+	})
+}
@@ -2,10 +2,13 @@ package admin
 
 import (
 	"crypto/rand"
+	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 	"walk-server/global"
 	"walk-server/model"
+	"walk-server/service/adminService"
 	"walk-server/utility"
 )
 
@@ -20,7 +23,6 @@ type CreateRouteAdminData struct {
 	PFAll   [][]Data `json:"pf_all"`
 	MGSHalf [][]Data `json:"mgs_half"`
 	MGSAll  [][]Data `json:"mgs_all"`
-	Secret  string   `json:"secret" binding:"required"`
 }
 
 const (
@@ -34,49 +36,95 @@ func CreateRouteAdmin(c *gin.Context) {
 		return
 	}
 
-	if postForm.Secret != global.Config.GetString("server.secret") {
-		utility.ResponseError(c, "密码错误")
-		return
-	}
-
 	admins := make([]model.Admin, 0)
+	passwords := make([]string, 0)
 
-	processData := func(data [][]Data, point int8) {
+	processData := func(data [][]Data, point int8) error {
 		for i := 0; i < len(data); i++ {
 			for j := 0; j < len(data[i]); j++ {
 				pwd, err := generateRandomPassword()
 				if err != nil {
-					utility.ResponseError(c, "密码生成错误: "+err.Error())
-					return
+					return err
+				}
+				hash, err := utility.HashPassword(pwd)
+				if err != nil {
+					return err
 				}
 				admins = append(admins, model.Admin{
-					Name:     data[i][j].Name,
-					Account:  data[i][j].Account,
-					Password: pwd,
-					Point:    point,
-					Route:    uint8(j),
+					Name:       data[i][j].Name,
+					Account:    data[i][j].Account,
+					Password:   hash,
+					Point:      point,
+					Route:      uint8(j),
+					Role:       model.RoleVolunteer,
+					MustChange: true,
 				})
+				passwords = append(passwords, pwd)
 			}
 		}
+		return nil
 	}
 
-	processData(postForm.ZH, 1)
-	processData(postForm.PFHalf, 2)
-	processData(postForm.PFAll, 3)
-	processData(postForm.MGSHalf, 4)
-	processData(postForm.MGSAll, 5)
+	for point, data := range [][][]Data{postForm.ZH, postForm.PFHalf, postForm.PFAll, postForm.MGSHalf, postForm.MGSAll} {
+		if err := processData(data, int8(point+1)); err != nil {
+			utility.ResponseError(c, "密码生成错误: "+err.Error())
+			return
+		}
+	}
 
-	result := global.DB.Create(&admins)
-	if result.Error != nil {
-		utility.ResponseError(c, "数据库错误: "+result.Error.Error())
+	// 密码只通过一次性凭证查看，凭证签发失败时不创建账号
+	var credentials []gin.H
+	var errIssue error
+	err := global.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&admins).Error; err != nil {
+			return err
+		}
+		credentials, errIssue = issueCredentials(admins, passwords)
+		return errIssue
+	})
+	if errIssue != nil {
+		utility.ResponseError(c, "服务错误")
+		return
+	} else if err != nil {
+		discardCredentials(credentials)
+		utility.ResponseError(c, "数据库错误: "+err.Error())
 		return
 	}
-
 	utility.ResponseSuccess(c, gin.H{
-		"admins": admins,
+		"admins": credentials,
 	})
 }
 
+// issueCredentials 为新密码生成一次性查看凭证
+func issueCredentials(admins []model.Admin, passwords []string) ([]gin.H, error) {
+	credentials := make([]gin.H, 0, len(admins))
+	for i, admin := range admins {
+		token, err := adminService.IssueCredential(admin, passwords[i])
+		if err != nil {
+			discardCredentials(credentials)
+			return nil, err
+		}
+		credentials = append(credentials, gin.H{
+			"admin_id": admin.ID,
+			"name":     admin.Name,
+			"account":  admin.Account,
+			"route":    admin.Route,
+			"point":    admin.Point,
+			"token":    token,
+		})
+	}
+	return credentials, nil
+}
+
+// discardCredentials 密码没有保存成功时作废已经签发的凭证
+func discardCredentials(credentials []gin.H) {
+	for _, credential := range credentials {
+		if token, ok := credential["token"].(string); ok {
+			_ = adminService.DiscardCredential(token)
+		}
+	}
+}
+
 // generateRandomString 生成一个指定长度的随机字符串，使用字母和数字
 func generateRandomString(n int) (string, error) {
 	// 创建一个字节切片来存储随机字节
@@ -96,5 +144,39 @@ func generateRandomString(n int) (string, error) {
 
 // generateRandomPassword 生成一个随机密码
 func generateRandomPassword() (string, error) {
-	return generateRandomString(6) // 6 个随机字母数字字符
+	return generateRandomString(10) // 10 个随机字母数字字符
+}
+
+type SetAdminRoleForm struct {
+	AdminID uint  `json:"admin_id" binding:"required"`
+	Role    uint8 `json:"role" binding:"required,oneof=1 2 3 4"` // 1 超级管理员，2 路线负责人，3 点位志愿者，4 观察员
+}
+
+// SetAdminRole 修改管理员角色
+func SetAdminRole(c *gin.Context) {
+	var postForm SetAdminRoleForm
+	if err := c.ShouldBindJSON(&postForm); err != nil {
+		utility.ResponseError(c, "参数错误")
+		return
+	}
+
+	user, _ := adminService.GetAdminByJWT(c)
+	if user.ID == postForm.AdminID {
+		utility.ResponseError(c, "不能修改自己的角色")
+		return
+	}
+
+	if _, err := adminService.GetAdminByID(postForm.AdminID); errors.Is(err, gorm.ErrRecordNotFound) {
+		utility.ResponseError(c, "管理员不存在")
+		return
+	} else if err != nil {
+		utility.ResponseError(c, "服务错误")
+		return
+	}
+
+	if err := adminService.UpdateRole(postForm.AdminID, postForm.Role); err != nil {
+		utility.ResponseError(c, "服务错误")
+		return
+	}
+	utility.ResponseSuccess(c, nil)
 }
@@ -0,0 +1,124 @@
+package admin
+
+import (
+	"errors"
+	"time"
+	"walk-server/constant"
+	"walk-server/global"
+	"walk-server/middleware"
+	"walk-server/model"
+	"walk-server/service/adminService"
+	"walk-server/utility"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type GetAnomaliesForm struct {
+	Route    uint8 `form:"route"`    // 路线，为空时查询全部路线
+	Reviewed *bool `form:"reviewed"` // 是否已复核，为空时查询全部
+}
+
+// GetAnomalies 获取扫码顺序异常列表
+func GetAnomalies(c *gin.Context) {
+	var postForm GetAnomaliesForm
+	if err := c.ShouldBindQuery(&postForm); err != nil {
+		utility.ResponseError(c, "参数错误")
+		return
+	}
+
+	admin, _ := adminService.GetAdminByJWT(c)
+	if postForm.Route == 0 && admin.Role != model.RoleSuper {
+		utility.ResponseError(c, "请选择路线")
+		return
+	}
+	if postForm.Route != 0 && !middleware.CheckRouteID(admin, postForm.Route) {
+		utility.ResponseError(c, "没有权限")
+		return
+	}
+
+	query := global.DB.Model(&model.ScanAnomaly{})
+	if postForm.Route != 0 {
+		query = query.Where("route = ?", postForm.Route)
+	}
+	if postForm.Reviewed != nil {
+		query = query.Where("reviewed = ?", *postForm.Reviewed)
+	}
+	var anomalies []model.ScanAnomaly
+	if err := query.Order("time DESC").Find(&anomalies).Error; err != nil {
+		utility.ResponseError(c, "获取失败，请稍后重试")
+		return
+	}
+
+	adminIDs := make([]uint, 0, len(anomalies))
+	teamIDs := make([]uint, 0, len(anomalies))
+	for _, anomaly := range anomalies {
+		adminIDs = append(adminIDs, anomaly.AdminID)
+		teamIDs = append(teamIDs, anomaly.TeamID)
+	}
+	admins, err := adminService.GetAdminsByIDs(adminIDs)
+	if err != nil {
+		utility.ResponseError(c, "获取失败，请稍后重试")
+		return
+	}
+	var teams []model.Team
+	global.DB.Where("id IN ?", teamIDs).Find(&teams)
+	teamNames := make(map[uint]string, len(teams))
+	for _, team := range teams {
+		teamNames[team.ID] = team.Name
+	}
+
+	results := make([]gin.H, 0, len(anomalies))
+	for _, anomaly := range anomalies {
+		results = append(results, gin.H{
+			"id":         anomaly.ID,
+			"team_id":    anomaly.TeamID,
+			"team_name":  teamNames[anomaly.TeamID],
+			"route":      anomaly.Route,
+			"type":       anomaly.Type,
+			"from":       constant.GetPointName(anomaly.Route, anomaly.FromPoint),
+			"to":         constant.GetPointName(anomaly.Route, anomaly.ToPoint),
+			"admin_name": admins[anomaly.AdminID].Name,
+			"reviewed":   anomaly.Reviewed,
+			"time":       anomaly.Time.Format(time.DateTime),
+		})
+	}
+
+	utility.ResponseSuccess(c, gin.H{
+		"anomalies": results,
+	})
+}
+
+type ReviewAnomalyForm struct {
+	ID uint `json:"id" binding:"required"`
+}
+
+// ReviewAnomaly 将异常标记为已复核
+func ReviewAnomaly(c *gin.Context) {
+	var postForm ReviewAnomalyForm
+	if err := c.ShouldBindJSON(&postForm); err != nil {
+		utility.ResponseError(c, "参数错误")
+		return
+	}
+
+	var anomaly model.ScanAnomaly
+	if err := global.DB.Take(&anomaly, postForm.ID).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+		utility.ResponseError(c, "异常记录不存在")
+		return
+	} else if err != nil {
+		utility.ResponseError(c, "服务错误")
+		return
+	}
+
+	user, _ := adminService.GetAdminByJWT(c)
+	if !middleware.CheckRouteID(user, anomaly.Route) {
+		utility.ResponseError(c, "没有该路线的权限")
+		return
+	}
+
+	if err := global.DB.Model(&anomaly).Update("reviewed", true).Error; err != nil {
+		utility.ResponseError(c, "服务错误")
+		return
+	}
+	utility.ResponseSuccess(c, nil)
+}
@@ -0,0 +1,157 @@
+package admin
+
+import (
+	"encoding/json"
+	"time"
+	"walk-server/global"
+	"walk-server/middleware"
+	"walk-server/model"
+	"walk-server/service/adminService"
+	"walk-server/state"
+	"walk-server/utility"
+
+	"github.com/gin-gonic/gin"
+)
+
+// teamState 审计日志中记录的队伍状态
+type teamState struct {
+	Status  state.TeamStatus            `json:"status"`
+	Point   int8                        `json:"point"`
+	Submit  bool                        `json:"submit"`
+	Members map[string]state.WalkStatus `json:"members"` // OpenID -> 毅行状态
+}
+
+func snapshotTeam(team *model.Team, persons []model.Person) teamState {
+	members := make(map[string]state.WalkStatus, len(persons))
+	for _, p := range persons {
+		members[p.OpenId] = p.WalkStatus
+	}
+	return teamState{
+		Status:  team.Status,
+		Point:   team.Point,
+		Submit:  team.Submit,
+		Members: members,
+	}
+}
+
+type GetAuditLogsForm struct {
+	AdminID  uint   `form:"admin_id"`
+	TeamID   uint   `form:"team_id"`
+	PersonID string `form:"person_id"`
+	Route    uint8  `form:"route"`
+	Action   string `form:"action"` // 接口路径，如 team/user_status
+	Start    string `form:"start"`  // 开始时间，格式 2006-01-02 15:04:05
+	End      string `form:"end"`    // 结束时间
+	Page     int    `form:"page,default=1" binding:"min=1"`
+	Size     int    `form:"size,default=50" binding:"min=1,max=200"`
+}
+
+// GetAuditLogs 按管理员、队伍、队员、路线和时间范围查询审计日志
+func GetAuditLogs(c *gin.Context) {
+	var postForm GetAuditLogsForm
+	if err := c.ShouldBindQuery(&postForm); err != nil {
+		utility.ResponseError(c, "参数错误")
+		return
+	}
+
+	// 非超级管理员只能查询自己有权限的路线，未指定路线时查询所在路线
+	admin, _ := adminService.GetAdminByJWT(c)
+	if admin.Role != model.RoleSuper {
+		if postForm.Route == 0 {
+			postForm.Route = admin.Route
+		}
+		if postForm.Route == 0 || !middleware.CheckRouteID(admin, postForm.Route) {
+			utility.ResponseError(c, "没有权限")
+			return
+		}
+	}
+
+	query := global.DB.Model(&model.Form{})
+	if postForm.AdminID != 0 {
+		query = query.Where("admin_id = ?", postForm.AdminID)
+	}
+	if postForm.Route != 0 {
+		query = query.Where("route = ?", postForm.Route)
+	}
+	if postForm.Action != "" {
+		query = query.Where("action = ?", postForm.Action)
+	}
+	if postForm.TeamID != 0 {
+		query = query.Where("id IN (?)", global.DB.Model(&model.FormTarget{}).Select("form_id").Where("team_id = ?", postForm.TeamID))
+	}
+	if postForm.PersonID != "" {
+		query = query.Where("id IN (?)", global.DB.Model(&model.FormTarget{}).Select("form_id").Where("person_id = ?", postForm.PersonID))
+	}
+	for _, t := range []struct {
+		value string
+		cond  string
+	}{{postForm.Start, "time >= ?"}, {postForm.End, "time <= ?"}} {
+		if t.value == "" {
+			continue
+		}
+		parsed, err := time.ParseInLocation(time.DateTime, t.value, time.Local)
+		if err != nil {
+			utility.ResponseError(c, "时间格式错误")
+			return
+		}
+		query = query.Where(t.cond, parsed)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		utility.ResponseError(c, "服务错误")
+		return
+	}
+	var forms []model.Form
+	err := query.Preload("Targets").
+		Order("time DESC").
+		Offset((postForm.Page - 1) * postForm.Size).
+		Limit(postForm.Size).
+		Find(&forms).Error
+	if err != nil {
+		utility.ResponseError(c, "服务错误")
+		return
+	}
+
+	adminIDs := make([]uint, 0, len(forms))
+	for _, form := range forms {
+		adminIDs = append(adminIDs, form.AdminID)
+	}
+	admins, err := adminService.GetAdminsByIDs(adminIDs)
+	if err != nil {
+		utility.ResponseError(c, "服务错误")
+		return
+	}
+
+	list := make([]gin.H, 0, len(forms))
+	for _, form := range forms {
+		list = append(list, gin.H{
+			"id":         form.ID,
+			"admin_id":   form.AdminID,
+			"admin_name": admins[form.AdminID].Name,
+			"route":      form.Route,
+			"point":      form.Point,
+			"action":     form.Action,
+			"method":     form.Method,
+			"data":       rawJSON(form.Data),
+			"before":     rawJSON(form.Before),
+			"after":      rawJSON(form.After),
+			"code":       form.Code,
+			"message":    form.Message,
+			"targets":    form.Targets,
+			"time":       form.Time,
+		})
+	}
+	utility.ResponseSuccess(c, gin.H{
+		"total": total,
+		"list":  list,
+	})
+}
+
+// rawJSON 将数据库中保存的 JSON 原样输出，空值输出为 null
+func rawJSON(data []byte) json.RawMessage {
+	if len(data) == 0 {
+		return json.RawMessage("null")
+	}
+	return data
+}
@@ -5,10 +5,10 @@ import (
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 	"log"
-	"strconv"
 	"walk-server/constant"
 	"walk-server/global"
 	"walk-server/service/adminService"
+	"walk-server/service/sessionService"
 	"walk-server/utility"
 )
 
@@ -35,6 +35,8 @@ type LoginResp struct {
 	Password     string `json:"-"`
 	Point        string `json:"point"`
 	Route        uint8  `json:"route"`
+	Role         uint8  `json:"role"`
+	MustChange   bool   `json:"must_change"` // 需要先修改密码才能使用其他功能
 }
 
 func AuthByPassword(c *gin.Context) {
@@ -55,7 +57,7 @@ func AuthByPassword(c *gin.Context) {
 		return
 	}
 
-	if user.Password != postForm.Password {
+	if !utility.CheckPassword(user.Password, postForm.Password) {
 		utility.ResponseError(c, "密码错误")
 		return
 	}
@@ -69,10 +71,12 @@ func AuthByPassword(c *gin.Context) {
 		user.WechatOpenID = session.OpenID
 		adminService.UpdateOpenID(*user)
 	}
-	var jwtData utility.JwtData
-	jwtData.OpenID = utility.AesEncrypt(strconv.Itoa(int(user.ID)), global.Config.GetString("server.AESSecret"))
 	// 生成 JWT
-	jwtToken, err := utility.GenerateStandardJwt(&jwtData)
+	jwtToken, refreshToken, err := sessionService.Create(c, utility.NewAdminClaims(user))
+	if err != nil {
+		utility.ResponseError(c, "服务错误")
+		return
+	}
 
 	utility.ResponseSuccess(c, gin.H{
 		"admin": LoginResp{
@@ -82,8 +86,11 @@ func AuthByPassword(c *gin.Context) {
 			Account:      user.Account,
 			Point:        constant.GetPointName(user.Route, user.Point),
 			Route:        user.Route,
+			Role:         user.Role,
+			MustChange:   user.MustChange,
 		},
-		"jwt": jwtToken,
+		"jwt":           jwtToken,
+		"refresh_token": refreshToken,
 	})
 }
 
@@ -108,10 +115,12 @@ func WeChatLogin(c *gin.Context) {
 		return
 	}
 
-	var jwtData utility.JwtData
-	jwtData.OpenID = utility.AesEncrypt(strconv.Itoa(int(user.ID)), global.Config.GetString("server.AESSecret"))
 	// 生成 JWT
-	jwtToken, err := utility.GenerateStandardJwt(&jwtData)
+	jwtToken, refreshToken, err := sessionService.Create(c, utility.NewAdminClaims(user))
+	if err != nil {
+		utility.ResponseError(c, "服务错误")
+		return
+	}
 
 	utility.ResponseSuccess(c, gin.H{
 		"admin": LoginResp{
@@ -121,8 +130,11 @@ func WeChatLogin(c *gin.Context) {
 			Account:      user.Account,
 			Point:        constant.GetPointName(user.Route, user.Point),
 			Route:        user.Route,
+			Role:         user.Role,
+			MustChange:   user.MustChange,
 		},
-		"jwt": jwtToken,
+		"jwt":           jwtToken,
+		"refresh_token": refreshToken,
 	})
 }
 
@@ -144,15 +156,17 @@ func AuthWithoutCode(c *gin.Context) {
 		return
 	}
 
-	if user.Password != postForm.Password {
+	if !utility.CheckPassword(user.Password, postForm.Password) {
 		utility.ResponseError(c, "密码错误")
 		return
 	}
 
-	var jwtData utility.JwtData
-	jwtData.OpenID = utility.AesEncrypt(strconv.Itoa(int(user.ID)), global.Config.GetString("server.AESSecret"))
 	// 生成 JWT
-	jwtToken, err := utility.GenerateStandardJwt(&jwtData)
+	jwtToken, refreshToken, err := sessionService.Create(c, utility.NewAdminClaims(user))
+	if err != nil {
+		utility.ResponseError(c, "服务错误")
+		return
+	}
 	utility.ResponseSuccess(c, gin.H{
 		"admin": LoginResp{
 			ID:           user.ID,
@@ -161,25 +175,15 @@ func AuthWithoutCode(c *gin.Context) {
 			Account:      user.Account,
 			Point:        constant.GetPointName(user.Route, user.Point),
 			Route:        user.Route,
+			Role:         user.Role,
+			MustChange:   user.MustChange,
 		},
-		"jwt": jwtToken,
+		"jwt":           jwtToken,
+		"refresh_token": refreshToken,
 	})
 }
 
-type BlockWithSecretForm struct {
-	Secret string `json:"secret" binding:"required"`
-}
-
+// BlockWithSecret 校验当前管理员是否为负责人，角色由路由中的 RequireRole 检查
 func BlockWithSecret(c *gin.Context) {
-	var postForm BlockWithSecretForm
-	err := c.ShouldBindJSON(&postForm)
-	if err != nil {
-		utility.ResponseError(c, "参数错误")
-		return
-	}
-	if postForm.Secret != global.Config.GetString("server.secret") {
-		utility.ResponseError(c, "密码错误")
-		return
-	}
 	utility.ResponseSuccess(c, nil)
 }
@@ -0,0 +1,156 @@
+package admin
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+	"walk-server/constant"
+	"walk-server/model"
+	"walk-server/service/dashboardService"
+	"walk-server/service/sessionService"
+	"walk-server/utility"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 没有扫码事件时定时推送一次全量计数，同时用作心跳
+const dashboardHeartbeat = 30 * time.Second
+
+// routeDetails 把路线的看板计数转换为大屏展示的格式：未开始、各点位、已结束、下撤
+func routeDetails(route constant.RouteInfo, counts map[string]int64) []RouteDetail {
+	pointNum := int8(constant.GetPointNum(route.ID))
+	details := make([]RouteDetail, 0, int(pointNum)+3)
+	details = append(details, RouteDetail{Count: counts[dashboardService.FieldStart], Label: "未开始"})
+	for i := int8(0); i <= pointNum; i++ {
+		details = append(details, RouteDetail{
+			Count: counts[dashboardService.PointField(i)],
+			Label: constant.GetPointName(route.ID, i),
+		})
+	}
+	details = append(details,
+		RouteDetail{Count: counts[dashboardService.FieldFinish], Label: "已结束"},
+		RouteDetail{Count: counts[dashboardService.FieldAbandon], Label: "下撤"},
+	)
+	return details
+}
+
+// dashboardDetail 获取全部路线的看板计数
+func dashboardDetail() (gin.H, error) {
+	data := gin.H{}
+	for _, route := range constant.GetRoutes() {
+		counts, err := dashboardService.GetCounts(route.ID)
+		if err != nil {
+			return nil, err
+		}
+		data[route.Code] = routeDetails(route, counts)
+	}
+	return data, nil
+}
+
+// publishScan 扫码事务提交后更新看板计数并推送扫码事件
+func publishScan(eventType string, team *model.Team, admin *model.Admin, num uint, before dashboardService.Counts, after dashboardService.Counts) {
+	dashboardService.Sync(before, after)
+	publishEvent(eventType, team, admin, num)
+}
+
+// publishEvent 推送扫码事件，num 为事件涉及的人数
+func publishEvent(eventType string, team *model.Team, admin *model.Admin, num uint) {
+	_ = dashboardService.Publish(dashboardService.Event{
+		Type:     eventType,
+		TeamID:   team.ID,
+		TeamName: team.Name,
+		Route:    team.Route,
+		Point:    team.Point,
+		Num:      num,
+		Admin:    admin.Name,
+		Time:     time.Now(),
+	})
+}
+
+// GetStreamToken 签发连接大屏推送使用的短期 token
+func GetStreamToken(c *gin.Context) {
+	token, err := utility.GenerateStreamToken(utility.GetJwtData(c))
+	if err != nil {
+		utility.ResponseError(c, "服务错误")
+		return
+	}
+	utility.ResponseSuccess(c, gin.H{
+		"token": token,
+	})
+}
+
+// DashboardStream 通过 Server-Sent Events 向大屏推送各路线点位人数和最新扫码事件
+// 浏览器的 EventSource 不能设置请求头，通过 GetStreamToken 获取的 token 放在查询参数中
+func DashboardStream(c *gin.Context) {
+	jwtData := utility.GetJwtData(c)
+	data, err := dashboardDetail()
+	if err != nil {
+		utility.ResponseError(c, "服务错误")
+		return
+	}
+	events, err := dashboardService.GetRecentEvents()
+	if err != nil {
+		utility.ResponseError(c, "服务错误")
+		return
+	}
+
+	pubsub := dashboardService.Subscribe()
+	defer pubsub.Close()
+	messages := pubsub.Channel()
+	ticker := time.NewTicker(dashboardHeartbeat)
+	defer ticker.Stop()
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no") // 关闭 nginx 缓冲
+	c.SSEvent("snapshot", gin.H{
+		"counts": data,
+		"events": events,
+	})
+	c.Writer.Flush()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case msg, ok := <-messages:
+			if !ok {
+				return false
+			}
+			var event dashboardService.Event
+			if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+				return true
+			}
+			route, ok := constant.GetRoute(event.Route)
+			if !ok {
+				return true
+			}
+			counts, err := dashboardService.GetCounts(route.ID)
+			if err != nil {
+				return true
+			}
+			c.SSEvent("event", gin.H{
+				"event":  event,
+				"route":  route.Code,
+				"counts": routeDetails(route, counts),
+			})
+		case <-ticker.C:
+			// 连接期间会话被撤销时断开推送
+			if sessionService.IsRevoked(jwtData.Session) {
+				return false
+			}
+			if data, err := dashboardDetail(); err == nil {
+				c.SSEvent("counts", data)
+			}
+		}
+		return true
+	})
+}
+
+// RebuildDashboard 根据数据库重新统计看板计数，用于修正手动改库等情况造成的偏差
+func RebuildDashboard(c *gin.Context) {
+	if err := dashboardService.Rebuild(); err != nil {
+		utility.ResponseError(c, "服务错误")
+		return
+	}
+	utility.ResponseSuccess(c, nil)
+}
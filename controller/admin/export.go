@@ -0,0 +1,190 @@
+package admin
+
+import (
+	"strconv"
+	"strings"
+	"time"
+	"walk-server/constant"
+	"walk-server/global"
+	"walk-server/middleware"
+	"walk-server/model"
+	"walk-server/service/adminService"
+	"walk-server/state"
+	"walk-server/utility"
+
+	"github.com/gin-gonic/gin"
+)
+
+// exportColumn 导出名单中可选的一列
+type exportColumn struct {
+	Header string
+	Value  func(team *model.Team, p *model.Person) any
+}
+
+var (
+	exportGenderNames = map[int8]string{1: "男", 2: "女"}
+	exportCampusNames = map[uint8]string{1: "朝晖", 2: "屏峰", 3: "莫干山"}
+	exportTypeNames   = map[uint8]string{1: "学生", 2: "教职工", 3: "校友"}
+	exportStatusNames = map[uint8]string{1: "队员", 2: "队长"}
+)
+
+// exportColumns 可以导出的列，通过 columns 参数按 key 选择
+var exportColumns = map[string]exportColumn{
+	"team_id":     {"队伍编号", func(t *model.Team, _ *model.Person) any { return t.ID }},
+	"team_name":   {"队伍名称", func(t *model.Team, _ *model.Person) any { return t.Name }},
+	"route":       {"路线", func(t *model.Team, _ *model.Person) any { return constant.GetRouteName(t.Route) }},
+	"team_status": {"队伍状态", func(t *model.Team, _ *model.Person) any { return t.Status.String() }},
+	"point":       {"当前点位", func(t *model.Team, _ *model.Person) any { return constant.GetPointName(t.Route, t.Point) }},
+	"role":        {"队伍担当", func(_ *model.Team, p *model.Person) any { return exportStatusNames[p.Status] }},
+	"name":        {"姓名", func(_ *model.Team, p *model.Person) any { return p.Name }},
+	"gender":      {"性别", func(_ *model.Team, p *model.Person) any { return exportGenderNames[p.Gender] }},
+	"stu_id":      {"学号", func(_ *model.Team, p *model.Person) any { return p.StuId }},
+	"tel":         {"电话", func(_ *model.Team, p *model.Person) any { return p.Tel }},
+	"qq":          {"QQ", func(_ *model.Team, p *model.Person) any { return p.Qq }},
+	"wechat":      {"微信", func(_ *model.Team, p *model.Person) any { return p.Wechat }},
+	"campus":      {"校区", func(_ *model.Team, p *model.Person) any { return exportCampusNames[p.Campus] }},
+	"college":     {"学院", func(_ *model.Team, p *model.Person) any { return p.College }},
+	"type":        {"参与者类型", func(_ *model.Team, p *model.Person) any { return exportTypeNames[p.Type] }},
+	"walk_status": {"毅行状态", func(_ *model.Team, p *model.Person) any { return p.WalkStatus.String() }},
+	"submit_day":  {"提交日期", func(t *model.Team, _ *model.Person) any { return exportSubmitDay(t) }},
+}
+
+// exportSubmitDay 队伍提交时占用名额的日期，管理员提交的队伍没有日期
+func exportSubmitDay(team *model.Team) string {
+	if team.SubmitDay == nil {
+		return "未记录"
+	}
+	return "第" + strconv.Itoa(int(*team.SubmitDay)+1) + "天"
+}
+
+// 默认导出的列
+var exportDefaultColumns = []string{"team_id", "team_name", "role", "name", "gender", "stu_id", "tel", "qq", "wechat", "campus", "college", "type"}
+
+type ExportUsersForm struct {
+	Group   string `form:"group,default=route" binding:"oneof=route point day"` // route 按路线分表，point 按队伍当前点位分表，用于各点位的紧急联系名单，day 按提交日期和路线分表
+	Columns string `form:"columns"`                                             // 逗号分隔的列 key，为空时导出默认列
+	Route   uint8  `form:"route"`                                               // 为 0 时导出全部路线，只有超级管理员可以导出全部
+	Type    uint8  `form:"type"`                                                // 参与者类型，为 0 时不限
+	Status  uint8  `form:"status"`                                              // 队伍状态，为 0 时不限
+	Submit  bool   `form:"submit,default=true"`                                 // 是否只导出已提交的队伍
+}
+
+// ExportUsers 按条件导出队伍和队员名单，生成的 Excel 直接在响应中返回
+func ExportUsers(c *gin.Context) {
+	var postForm ExportUsersForm
+	if err := c.ShouldBindQuery(&postForm); err != nil {
+		utility.ResponseError(c, "参数错误")
+		return
+	}
+
+	user, _ := adminService.GetAdminByJWT(c)
+	if postForm.Route == 0 && user.Role != model.RoleSuper {
+		utility.ResponseError(c, "请选择路线")
+		return
+	}
+	if postForm.Route != 0 && !middleware.CheckRouteID(user, postForm.Route) {
+		utility.ResponseError(c, "没有权限")
+		return
+	}
+
+	keys := exportDefaultColumns
+	if postForm.Columns != "" {
+		keys = strings.Split(postForm.Columns, ",")
+	}
+	columns := make([]exportColumn, 0, len(keys))
+	headers := make([]string, 0, len(keys))
+	for _, key := range keys {
+		column, ok := exportColumns[strings.TrimSpace(key)]
+		if !ok {
+			utility.ResponseError(c, "未知的列："+key)
+			return
+		}
+		columns = append(columns, column)
+		headers = append(headers, column.Header)
+	}
+
+	teamQuery := global.DB
+	if postForm.Group == "day" {
+		teamQuery = teamQuery.Order("submit_day")
+	}
+	teamQuery = teamQuery.Order("route")
+	if postForm.Submit {
+		teamQuery = teamQuery.Where("submit = ?", true)
+	}
+	if postForm.Route != 0 {
+		teamQuery = teamQuery.Where("route = ?", postForm.Route)
+	}
+	if postForm.Status != 0 {
+		teamQuery = teamQuery.Where("status = ?", postForm.Status)
+	}
+	if postForm.Group == "point" {
+		teamQuery = teamQuery.Where("status IN ?", []state.TeamStatus{state.TeamStarted, state.TeamInProgress}).Order("point")
+	}
+	var teams []model.Team
+	if err := teamQuery.Order("id").Find(&teams).Error; err != nil {
+		utility.ResponseError(c, "服务错误")
+		return
+	}
+
+	teamIDs := make([]uint, 0, len(teams))
+	for _, team := range teams {
+		teamIDs = append(teamIDs, team.ID)
+	}
+	var persons []model.Person
+	personQuery := global.DB.Where("team_id IN ?", teamIDs).Order("team_id, status DESC")
+	if postForm.Type != 0 {
+		personQuery = personQuery.Where("type = ?", postForm.Type)
+	}
+	if len(teamIDs) > 0 {
+		if err := personQuery.Find(&persons).Error; err != nil {
+			utility.ResponseError(c, "服务错误")
+			return
+		}
+	}
+	members := make(map[int][]model.Person, len(teams))
+	for _, p := range persons {
+		members[p.TeamId] = append(members[p.TeamId], p)
+	}
+
+	// 按路线、点位或提交日期分组，每组一个工作表
+	var names []string
+	groups := make(map[string][][]any)
+	for i := range teams {
+		team := &teams[i]
+		name := constant.GetRouteName(team.Route)
+		if postForm.Group == "point" {
+			name += "-" + constant.GetPointName(team.Route, team.Point)
+		} else if postForm.Group == "day" {
+			name = exportSubmitDay(team) + "-" + name
+		}
+		for j := range members[int(team.ID)] {
+			p := &members[int(team.ID)][j]
+			row := make([]any, 0, len(columns))
+			for _, column := range columns {
+				row = append(row, column.Value(team, p))
+			}
+			if _, ok := groups[name]; !ok {
+				names = append(names, name)
+			}
+			groups[name] = append(groups[name], row)
+		}
+	}
+	if len(names) == 0 {
+		utility.ResponseError(c, "没有符合条件的数据")
+		return
+	}
+
+	// 路线和点位名称可能很长或者包含不允许的字符，转换为合法的工作表名称
+	sheetNames := utility.SheetNames(names)
+	sheets := make([]utility.Sheet, 0, len(names))
+	for i, name := range names {
+		sheets = append(sheets, utility.Sheet{
+			Name:    sheetNames[i],
+			Headers: headers,
+			Rows:    groups[name],
+		})
+	}
+
+	fileName := "毅行名单" + time.Now().Format("20060102150405") + ".xlsx"
+	utility.ResponseExcel(c, fileName, utility.File{Sheets: sheets})
+}
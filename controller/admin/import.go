@@ -0,0 +1,247 @@
+package admin
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"walk-server/model"
+	"walk-server/privacy"
+	"walk-server/service/userService"
+	"walk-server/state"
+	"walk-server/utility"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 名单必须包含的列，另有可以为空的学院和学号列
+var importRequired = []string{"姓名", "身份证号", "电话", "类型", "校区"}
+
+var (
+	importCampusMap = map[string]uint8{"朝晖": 1, "屏峰": 2, "莫干山": 3}
+	importTypeMap   = map[string]uint8{"教职工": 2, "校友": 3}
+)
+
+// ImportRowError 名单中一行的校验结果
+type ImportRowError struct {
+	Row    int      `json:"row"` // 文件中的行号，列名为第 1 行
+	Name   string   `json:"name"`
+	Errors []string `json:"errors"`
+}
+
+type ImportUsersForm struct {
+	Commit bool `form:"commit"` // 为 false 时只校验不写入
+}
+
+// parseImportEnum 解析校区、类型等列，既可以填名称也可以填编号
+func parseImportEnum(value string, names map[string]uint8) (uint8, bool) {
+	if v, ok := names[value]; ok {
+		return v, true
+	}
+	n, err := strconv.ParseUint(value, 10, 8)
+	if err != nil {
+		return 0, false
+	}
+	for _, v := range names {
+		if v == uint8(n) {
+			return v, true
+		}
+	}
+	return 0, false
+}
+
+func importKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// ImportUsers 从 Excel 或 CSV 批量导入教职工和校友名单，先试运行校验，确认后再写入
+func ImportUsers(c *gin.Context) {
+	var postForm ImportUsersForm
+	if err := c.ShouldBind(&postForm); err != nil {
+		utility.ResponseError(c, "参数错误")
+		return
+	}
+	header, err := c.FormFile("file")
+	if err != nil {
+		utility.ResponseError(c, "请上传名单文件")
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		utility.ResponseError(c, "文件读取失败")
+		return
+	}
+	defer file.Close()
+
+	rows, err := utility.ReadTable(header.Filename, file)
+	if errors.Is(err, utility.ErrUnsupportedFile) {
+		utility.ResponseError(c, err.Error())
+		return
+	} else if err != nil || len(rows) == 0 {
+		utility.ResponseError(c, "文件解析失败")
+		return
+	}
+
+	// 按列名定位各列
+	columns := make(map[string]int)
+	for i, name := range rows[0] {
+		columns[strings.TrimSpace(name)] = i
+	}
+	for _, name := range importRequired {
+		if _, ok := columns[name]; !ok {
+			utility.ResponseError(c, "缺少列："+name)
+			return
+		}
+	}
+	cell := func(row []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+
+	var (
+		total      int
+		persons    []model.Person
+		personRows []int // persons 中每个成员对应的行号
+		reports    = make(map[int]*ImportRowError)
+		identities = make(map[string]int)
+		tels       = make(map[string]int)
+		stuIDs     = make(map[string]int)
+	)
+	addError := func(row int, name string, msg string) {
+		if reports[row] == nil {
+			reports[row] = &ImportRowError{Row: row, Name: name}
+		}
+		reports[row].Errors = append(reports[row].Errors, msg)
+	}
+
+	for i, row := range rows[1:] {
+		line := i + 2
+		if slices.IndexFunc(row, func(v string) bool { return strings.TrimSpace(v) != "" }) < 0 {
+			continue // 跳过空行
+		}
+		total++
+
+		name := cell(row, "姓名")
+		identity := utility.NormalizeIdentity(cell(row, "身份证号"))
+		tel := cell(row, "电话")
+		stuID := cell(row, "学号")
+		if name == "" {
+			addError(line, name, "姓名为空")
+		}
+		if !utility.IsValidIdentity(identity) {
+			addError(line, name, "身份证号无效")
+		} else if first, ok := identities[identity]; ok {
+			addError(line, name, "身份证号与第 "+strconv.Itoa(first)+" 行重复")
+		} else {
+			identities[identity] = line
+		}
+		if !utility.IsValidPhone(tel) {
+			addError(line, name, "电话无效")
+		} else if first, ok := tels[tel]; ok {
+			addError(line, name, "电话与第 "+strconv.Itoa(first)+" 行重复")
+		} else {
+			tels[tel] = line
+		}
+		if stuID != "" {
+			if first, ok := stuIDs[stuID]; ok {
+				addError(line, name, "学号与第 "+strconv.Itoa(first)+" 行重复")
+			} else {
+				stuIDs[stuID] = line
+			}
+		}
+		personType, ok := parseImportEnum(cell(row, "类型"), importTypeMap)
+		if !ok {
+			addError(line, name, "类型只能为教职工或校友")
+		}
+		campus, ok := parseImportEnum(cell(row, "校区"), importCampusMap)
+		if !ok {
+			addError(line, name, "校区无效")
+		}
+		if reports[line] != nil {
+			continue
+		}
+
+		placeholder, err := utility.RandomToken(16)
+		if err != nil {
+			utility.ResponseError(c, "服务错误")
+			return
+		}
+		persons = append(persons, model.Person{
+			OpenId:     userService.ImportedOpenIDPrefix + placeholder,
+			Name:       name,
+			Gender:     utility.GenderFromIdentity(identity),
+			StuId:      stuID,
+			Campus:     campus,
+			Identity:   identity,
+			Status:     0,
+			College:    cell(row, "学院"),
+			Tel:        tel,
+			CreatedOp:  2,
+			JoinOp:     5,
+			TeamId:     -1,
+			Type:       personType,
+			WalkStatus: state.WalkNotStarted,
+		})
+		personRows = append(personRows, line)
+	}
+
+	// 检查是否与已有成员冲突
+	if len(identities) > 0 {
+		conflicts, err := userService.GetConflictUsers(importKeys(identities), importKeys(tels), importKeys(stuIDs))
+		if err != nil {
+			utility.ResponseError(c, "服务错误")
+			return
+		}
+		indexes := make(map[string]int, len(identities))
+		for identity, line := range identities {
+			indexes[privacy.BlindIndex(identity)] = line
+		}
+		for _, p := range conflicts {
+			if line, ok := indexes[p.IdIndex]; ok {
+				addError(line, "", "身份证号已存在")
+			}
+			if line, ok := tels[p.Tel]; ok {
+				addError(line, "", "电话已存在")
+			}
+			if line, ok := stuIDs[p.StuId]; ok && p.StuId != "" {
+				addError(line, "", "学号已存在")
+			}
+		}
+	}
+
+	valid := make([]model.Person, 0, len(persons))
+	for i, p := range persons {
+		if report := reports[personRows[i]]; report != nil {
+			report.Name = p.Name
+			continue
+		}
+		valid = append(valid, p)
+	}
+	errorList := make([]*ImportRowError, 0, len(reports))
+	for _, report := range reports {
+		errorList = append(errorList, report)
+	}
+	slices.SortFunc(errorList, func(a, b *ImportRowError) int { return a.Row - b.Row })
+
+	imported := 0
+	if postForm.Commit && len(valid) > 0 {
+		if err := userService.ImportUsers(valid); err != nil {
+			utility.ResponseError(c, "导入失败，请重新试运行后再导入")
+			return
+		}
+		imported = len(valid)
+	}
+	utility.ResponseSuccess(c, gin.H{
+		"total":    total,
+		"valid":    len(valid),
+		"imported": imported,
+		"errors":   errorList,
+	})
+}
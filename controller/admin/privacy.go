@@ -0,0 +1,89 @@
+package admin
+
+import (
+	"errors"
+	"walk-server/middleware"
+	"walk-server/model"
+	"walk-server/privacy"
+	"walk-server/service/adminService"
+	"walk-server/service/userService"
+	"walk-server/utility"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// canViewPrivacy 超级管理员和路线负责人可以在名单中查看完整的电话和学号，其余角色只能看到打码后的值
+func canViewPrivacy(admin *model.Admin) bool {
+	return admin.HasRole(model.RoleSuper, model.RoleRouteLead)
+}
+
+// displayTel 没有权限的管理员只能看到打码后的电话
+func displayTel(admin *model.Admin, tel string) string {
+	if canViewPrivacy(admin) {
+		return tel
+	}
+	return privacy.MaskTel(tel)
+}
+
+func maskUser(user *User) {
+	user.Tel = privacy.MaskTel(user.Tel)
+	user.StuId = privacy.MaskStuID(user.StuId)
+}
+
+type RevealPersonForm struct {
+	OpenID string `json:"open_id" binding:"required"`
+	Field  string `json:"field" binding:"required,oneof=tel stu_id identity"`
+}
+
+// RevealPerson 查看队员完整的电话、学号或身份证号，每次查看都会记录在审计日志中
+// 扫码人员只能查看本路线队员的电话，学号和身份证号只有路线负责人和超级管理员可以查看
+func RevealPerson(c *gin.Context) {
+	var postForm RevealPersonForm
+	if err := c.ShouldBindJSON(&postForm); err != nil {
+		utility.ResponseError(c, "参数错误")
+		return
+	}
+
+	user, _ := adminService.GetAdminByJWT(c)
+	if postForm.Field != "tel" && !canViewPrivacy(user) {
+		utility.ResponseError(c, "没有权限")
+		return
+	}
+
+	person, err := userService.GetUserByOpenID(postForm.OpenID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		utility.ResponseError(c, "用户不存在")
+		return
+	} else if err != nil {
+		utility.ResponseError(c, "服务错误")
+		return
+	}
+	var teamID uint
+	if person.TeamId > 0 {
+		teamID = uint(person.TeamId)
+	}
+	middleware.AuditTarget(c, teamID, person.OpenId)
+
+	// 未加入队伍的用户只有超级管理员可以查看
+	if user.Role != model.RoleSuper {
+		team, err := model.GetTeamInfo(teamID)
+		if err != nil || !middleware.CheckRoute(user, team) {
+			utility.ResponseError(c, "该队员为其他路线")
+			return
+		}
+	}
+
+	var value string
+	switch postForm.Field {
+	case "tel":
+		value = person.Tel
+	case "stu_id":
+		value = person.StuId
+	case "identity":
+		value = person.Identity
+	}
+	utility.ResponseSuccess(c, gin.H{
+		"value": value,
+	})
+}
@@ -0,0 +1,69 @@
+package admin
+
+import (
+	"errors"
+	"walk-server/constant"
+	"walk-server/middleware"
+	"walk-server/model"
+	"walk-server/service/quotaService"
+	"walk-server/utility"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetQuotas 获取每天各路线的名额总数、剩余、已使用和候补数量
+func GetQuotas(c *gin.Context) {
+	list, err := quotaService.List()
+	if err != nil {
+		utility.ResponseError(c, "服务错误")
+		return
+	}
+	utility.ResponseSuccess(c, gin.H{
+		"list": list,
+	})
+}
+
+type SaveQuotaForm struct {
+	Day   *uint8 `json:"day" binding:"required"`
+	Route uint8  `json:"route" binding:"required"`
+	Total *int   `json:"total"` // 修改后的名额总数
+	Delta int    `json:"delta"` // 没有 total 时在当前总数上增减
+}
+
+// SaveQuota 修改某天某条路线的名额总数，剩余名额随之原子调整，名额增加时自动提交候补的队伍
+func SaveQuota(c *gin.Context) {
+	var postForm SaveQuotaForm
+	if err := c.ShouldBindJSON(&postForm); err != nil {
+		utility.ResponseError(c, "参数错误")
+		return
+	}
+	if !constant.RouteExists(postForm.Route) {
+		utility.ResponseError(c, "路线不存在")
+		return
+	}
+
+	before, _ := model.GetQuota(*postForm.Day, postForm.Route)
+
+	var status *quotaService.Status
+	var err error
+	if postForm.Total != nil {
+		status, err = quotaService.SetTotal(*postForm.Day, postForm.Route, *postForm.Total)
+	} else {
+		status, err = quotaService.Adjust(*postForm.Day, postForm.Route, postForm.Delta)
+	}
+	if errors.Is(err, quotaService.ErrBelowUsed) {
+		utility.ResponseError(c, "名额总数不能少于已提交的队伍数")
+		return
+	} else if errors.Is(err, quotaService.ErrInvalidTotal) {
+		utility.ResponseError(c, "名额总数不能为负数")
+		return
+	} else if err != nil {
+		utility.ResponseError(c, "服务错误")
+		return
+	}
+
+	middleware.AuditChange(c, before, model.Quota{Day: status.Day, Route: status.Route, Total: status.Total})
+	utility.ResponseSuccess(c, gin.H{
+		"quota": status,
+	})
+}
@@ -0,0 +1,36 @@
+package admin
+
+import (
+	"walk-server/service/reconcileService"
+	"walk-server/utility"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetReconcileReport 获取最近一次 Redis 与 MySQL 比对的报告，refresh=true 时重新比对但不修复
+func GetReconcileReport(c *gin.Context) {
+	report := reconcileService.Last()
+	if report == nil || c.Query("refresh") == "true" {
+		var err error
+		report, err = reconcileService.Run(false)
+		if err != nil {
+			utility.ResponseError(c, "服务错误")
+			return
+		}
+	}
+	utility.ResponseSuccess(c, gin.H{
+		"report": report,
+	})
+}
+
+// RunReconcile 立即比对并按 MySQL 修复 Redis 中的提交集合
+func RunReconcile(c *gin.Context) {
+	report, err := reconcileService.Run(true)
+	if err != nil {
+		utility.ResponseError(c, "服务错误")
+		return
+	}
+	utility.ResponseSuccess(c, gin.H{
+		"report": report,
+	})
+}
@@ -0,0 +1,217 @@
+package admin
+
+import (
+	"errors"
+	"walk-server/constant"
+	"walk-server/global"
+	"walk-server/model"
+	"walk-server/service/routeService"
+	"walk-server/utility"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetRoutes 获取全部路线及点位
+func GetRoutes(c *gin.Context) {
+	routes := make([]gin.H, 0)
+	for _, route := range constant.GetRoutes() {
+		points := make([]gin.H, 0, len(route.Points))
+		for i := int8(0); i <= int8(constant.GetPointNum(route.ID)); i++ {
+			name, ok := route.Points[i]
+			if !ok {
+				continue
+			}
+			points = append(points, gin.H{
+				"point":   i,
+				"name":    name,
+				"site_id": route.Sites[i],
+			})
+		}
+		routes = append(routes, gin.H{
+			"id":     route.ID,
+			"code":   route.Code,
+			"name":   route.Name,
+			"campus": route.Campus,
+			"points": points,
+		})
+	}
+
+	sites, err := model.GetSites()
+	if err != nil {
+		utility.ResponseError(c, "服务错误")
+		return
+	}
+
+	utility.ResponseSuccess(c, gin.H{
+		"routes": routes,
+		"sites":  sites,
+	})
+}
+
+type SaveRouteForm struct {
+	ID     uint8  `json:"id" binding:"required"`
+	Code   string `json:"code" binding:"required"`
+	Name   string `json:"name" binding:"required"`
+	Campus uint8  `json:"campus" binding:"required,oneof=1 2 3"`
+}
+
+// SaveRoute 新增或修改路线
+func SaveRoute(c *gin.Context) {
+	var postForm SaveRouteForm
+	if err := c.ShouldBindJSON(&postForm); err != nil {
+		utility.ResponseError(c, "参数错误")
+		return
+	}
+
+	err := routeService.SaveRoute(model.Route{
+		ID:     postForm.ID,
+		Code:   postForm.Code,
+		Name:   postForm.Name,
+		Campus: postForm.Campus,
+	})
+	if err != nil {
+		utility.ResponseError(c, "服务错误")
+		return
+	}
+	utility.ResponseSuccess(c, nil)
+}
+
+type DeleteRouteForm struct {
+	ID uint8 `json:"id" binding:"required"`
+}
+
+// DeleteRoute 删除路线，已有队伍报名的路线不能删除
+func DeleteRoute(c *gin.Context) {
+	var postForm DeleteRouteForm
+	if err := c.ShouldBindJSON(&postForm); err != nil {
+		utility.ResponseError(c, "参数错误")
+		return
+	}
+
+	var count int64
+	global.DB.Model(&model.Team{}).Where("route = ?", postForm.ID).Count(&count)
+	if count > 0 {
+		utility.ResponseError(c, "该路线已有队伍，无法删除")
+		return
+	}
+
+	if err := routeService.DeleteRoute(postForm.ID); err != nil {
+		utility.ResponseError(c, "服务错误")
+		return
+	}
+	utility.ResponseSuccess(c, nil)
+}
+
+type SaveSiteForm struct {
+	ID     uint   `json:"id"` // 为空时新增地点
+	Name   string `json:"name" binding:"required"`
+	Campus uint8  `json:"campus" binding:"required,oneof=1 2 3"`
+}
+
+// SaveSite 新增或修改地点
+func SaveSite(c *gin.Context) {
+	var postForm SaveSiteForm
+	if err := c.ShouldBindJSON(&postForm); err != nil {
+		utility.ResponseError(c, "参数错误")
+		return
+	}
+
+	err := routeService.SaveSite(model.Site{
+		ID:     postForm.ID,
+		Name:   postForm.Name,
+		Campus: postForm.Campus,
+	})
+	if err != nil {
+		utility.ResponseError(c, "服务错误")
+		return
+	}
+	utility.ResponseSuccess(c, nil)
+}
+
+type DeleteSiteForm struct {
+	ID uint `json:"id" binding:"required"`
+}
+
+// DeleteSite 删除地点
+func DeleteSite(c *gin.Context) {
+	var postForm DeleteSiteForm
+	if err := c.ShouldBindJSON(&postForm); err != nil {
+		utility.ResponseError(c, "参数错误")
+		return
+	}
+
+	if err := routeService.DeleteSite(postForm.ID); err != nil {
+		utility.ResponseError(c, "服务错误")
+		return
+	}
+	utility.ResponseSuccess(c, nil)
+}
+
+type SaveCheckpointForm struct {
+	RouteID uint8  `json:"route_id" binding:"required"`
+	Point   *int8  `json:"point" binding:"required,min=0"`
+	SiteID  uint   `json:"site_id" binding:"required"`
+	Name    string `json:"name"` // 为空时使用地点名称
+}
+
+// SaveCheckpoint 新增或修改路线上的点位
+func SaveCheckpoint(c *gin.Context) {
+	var postForm SaveCheckpointForm
+	if err := c.ShouldBindJSON(&postForm); err != nil {
+		utility.ResponseError(c, "参数错误")
+		return
+	}
+
+	if _, err := routeService.GetRouteByID(postForm.RouteID); errors.Is(err, gorm.ErrRecordNotFound) {
+		utility.ResponseError(c, "路线不存在")
+		return
+	} else if err != nil {
+		utility.ResponseError(c, "服务错误")
+		return
+	}
+
+	site, err := routeService.GetSiteByID(postForm.SiteID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		utility.ResponseError(c, "地点不存在")
+		return
+	} else if err != nil {
+		utility.ResponseError(c, "服务错误")
+		return
+	}
+	if postForm.Name == "" {
+		postForm.Name = site.Name
+	}
+
+	err = routeService.SaveCheckpoint(model.Checkpoint{
+		RouteID: postForm.RouteID,
+		Point:   *postForm.Point,
+		SiteID:  site.ID,
+		Name:    postForm.Name,
+	})
+	if err != nil {
+		utility.ResponseError(c, "服务错误")
+		return
+	}
+	utility.ResponseSuccess(c, nil)
+}
+
+type DeleteCheckpointForm struct {
+	RouteID uint8 `json:"route_id" binding:"required"`
+	Point   *int8 `json:"point" binding:"required,min=0"`
+}
+
+// DeleteCheckpoint 删除路线上的点位
+func DeleteCheckpoint(c *gin.Context) {
+	var postForm DeleteCheckpointForm
+	if err := c.ShouldBindJSON(&postForm); err != nil {
+		utility.ResponseError(c, "参数错误")
+		return
+	}
+
+	if err := routeService.DeleteCheckpoint(postForm.RouteID, *postForm.Point); err != nil {
+		utility.ResponseError(c, "服务错误")
+		return
+	}
+	utility.ResponseSuccess(c, nil)
+}
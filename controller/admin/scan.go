@@ -0,0 +1,28 @@
+package admin
+
+import (
+	"errors"
+	"walk-server/model"
+	"walk-server/utility"
+
+	"github.com/gin-gonic/gin"
+)
+
+// scanError 扫码事务中的业务错误，错误信息直接返回给管理员
+type scanError string
+
+func (e scanError) Error() string {
+	return string(e)
+}
+
+// responseScanError 根据扫码事务返回的错误响应管理员
+func responseScanError(c *gin.Context, err error) {
+	var msg scanError
+	if errors.As(err, &msg) {
+		utility.ResponseError(c, msg.Error())
+	} else if errors.Is(err, model.ErrVersionConflict) {
+		utility.ResponseError(c, "队伍状态已被其他管理员更新，请重新扫码")
+	} else {
+		utility.ResponseError(c, "服务错误，请联系负责人")
+	}
+}
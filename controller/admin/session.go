@@ -0,0 +1,211 @@
+package admin
+
+import (
+	"errors"
+	"walk-server/middleware"
+	"walk-server/model"
+	"walk-server/service/adminService"
+	"walk-server/service/sessionService"
+	"walk-server/service/userService"
+	"walk-server/utility"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type RefreshTokenForm struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// RefreshToken 管理员使用 refresh token 换取新的 access token，新 token 中的角色和路线以数据库为准
+func RefreshToken(c *gin.Context) {
+	var postForm RefreshTokenForm
+	if err := c.ShouldBindJSON(&postForm); err != nil {
+		utility.ResponseError(c, "参数错误")
+		return
+	}
+
+	jwtToken, refreshToken, err := sessionService.Refresh(postForm.RefreshToken, utility.KindAdmin)
+	if errors.Is(err, sessionService.ErrSessionExpired) || errors.Is(err, sessionService.ErrRefreshReused) {
+		utility.ResponseError(c, "登录已失效，请重新登录")
+		return
+	} else if err != nil {
+		utility.ResponseError(c, "服务错误")
+		return
+	}
+	utility.ResponseSuccess(c, gin.H{
+		"jwt":           jwtToken,
+		"refresh_token": refreshToken,
+	})
+}
+
+// getSessionAdmin 获取要查看会话的管理员，为空时是自己，其他账号按重置密码的规则检查权限
+func getSessionAdmin(c *gin.Context, id uint) (*model.Admin, bool) {
+	user, _ := adminService.GetAdminByJWT(c)
+	if id == 0 || id == user.ID {
+		return user, true
+	}
+	return getManagedAdmin(c, id)
+}
+
+type AdminSessionsForm struct {
+	AdminID uint `form:"admin_id"` // 为空时查看自己的会话
+}
+
+// GetAdminSessions 获取管理员全部有效的登录会话
+func GetAdminSessions(c *gin.Context) {
+	var postForm AdminSessionsForm
+	if err := c.ShouldBindQuery(&postForm); err != nil {
+		utility.ResponseError(c, "参数错误")
+		return
+	}
+	target, ok := getSessionAdmin(c, postForm.AdminID)
+	if !ok {
+		return
+	}
+
+	sessions, err := sessionService.List(utility.KindAdmin, sessionService.AdminSubject(target.ID))
+	if err != nil {
+		utility.ResponseError(c, "服务错误")
+		return
+	}
+	current := utility.GetJwtData(c).Session
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == current
+	}
+	utility.ResponseSuccess(c, gin.H{
+		"sessions": sessions,
+	})
+}
+
+type RevokeAdminSessionForm struct {
+	AdminID   uint   `json:"admin_id"`   // 为空时操作自己的会话
+	SessionID string `json:"session_id"` // 为空时退出当前会话，或配合 all 使用
+	All       bool   `json:"all"`        // 撤销全部会话，操作自己时保留当前会话
+}
+
+// RevokeAdminSessions 撤销管理员的登录会话，用于设备丢失等情况
+func RevokeAdminSessions(c *gin.Context) {
+	var postForm RevokeAdminSessionForm
+	if err := c.ShouldBindJSON(&postForm); err != nil {
+		utility.ResponseError(c, "参数错误")
+		return
+	}
+	target, ok := getSessionAdmin(c, postForm.AdminID)
+	if !ok {
+		return
+	}
+
+	current := utility.GetJwtData(c).Session
+	self := target.ID == utility.GetJwtData(c).AdminID
+	subject := sessionService.AdminSubject(target.ID)
+	var err error
+	switch {
+	case postForm.All && self:
+		err = sessionService.RevokeAll(utility.KindAdmin, subject, current)
+	case postForm.All:
+		err = sessionService.RevokeAll(utility.KindAdmin, subject, "")
+	case postForm.SessionID != "":
+		if !sessionService.Owns(utility.KindAdmin, subject, postForm.SessionID) {
+			utility.ResponseError(c, "会话不存在")
+			return
+		}
+		err = sessionService.Revoke(postForm.SessionID)
+	case self:
+		err = sessionService.Revoke(current)
+	default:
+		utility.ResponseError(c, "参数错误")
+		return
+	}
+	if err != nil {
+		utility.ResponseError(c, "服务错误")
+		return
+	}
+	utility.ResponseSuccess(c, nil)
+}
+
+// getSessionPerson 获取要查看会话的队员，路线负责人只能查看本路线的队员，未加入队伍的用户只有超级管理员可以查看
+func getSessionPerson(c *gin.Context, openID string) (*model.Person, bool) {
+	person, err := userService.GetUserByOpenID(openID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		utility.ResponseError(c, "用户不存在")
+		return nil, false
+	} else if err != nil {
+		utility.ResponseError(c, "服务错误")
+		return nil, false
+	}
+	var teamID uint
+	if person.TeamId > 0 {
+		teamID = uint(person.TeamId)
+	}
+	middleware.AuditTarget(c, teamID, person.OpenId)
+
+	user, _ := adminService.GetAdminByJWT(c)
+	if user.Role != model.RoleSuper {
+		team, err := model.GetTeamInfo(teamID)
+		if err != nil || !middleware.CheckRoute(user, team) {
+			utility.ResponseError(c, "该队员为其他路线")
+			return nil, false
+		}
+	}
+	return person, true
+}
+
+type UserSessionsForm struct {
+	OpenID string `form:"open_id" binding:"required"`
+}
+
+// GetUserSessions 获取队员全部有效的登录会话
+func GetUserSessions(c *gin.Context) {
+	var postForm UserSessionsForm
+	if err := c.ShouldBindQuery(&postForm); err != nil {
+		utility.ResponseError(c, "参数错误")
+		return
+	}
+	person, ok := getSessionPerson(c, postForm.OpenID)
+	if !ok {
+		return
+	}
+
+	sessions, err := sessionService.List(utility.KindUser, person.OpenId)
+	if err != nil {
+		utility.ResponseError(c, "服务错误")
+		return
+	}
+	utility.ResponseSuccess(c, gin.H{
+		"sessions": sessions,
+	})
+}
+
+type RevokeUserSessionForm struct {
+	OpenID    string `json:"open_id" binding:"required"`
+	SessionID string `json:"session_id"` // 为空时撤销全部会话
+}
+
+// RevokeUserSessions 撤销队员的登录会话，用于登录凭证泄漏等情况
+func RevokeUserSessions(c *gin.Context) {
+	var postForm RevokeUserSessionForm
+	if err := c.ShouldBindJSON(&postForm); err != nil {
+		utility.ResponseError(c, "参数错误")
+		return
+	}
+	person, ok := getSessionPerson(c, postForm.OpenID)
+	if !ok {
+		return
+	}
+
+	var err error
+	if postForm.SessionID == "" {
+		err = sessionService.RevokeAll(utility.KindUser, person.OpenId, "")
+	} else if sessionService.Owns(utility.KindUser, person.OpenId, postForm.SessionID) {
+		err = sessionService.Revoke(postForm.SessionID)
+	} else {
+		utility.ResponseError(c, "会话不存在")
+		return
+	}
+	if err != nil {
+		utility.ResponseError(c, "服务错误")
+		return
+	}
+	utility.ResponseSuccess(c, nil)
+}
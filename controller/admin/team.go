@@ -9,8 +9,12 @@ import (
 	"walk-server/middleware"
 	"walk-server/model"
 	"walk-server/service/adminService"
+	"walk-server/service/dashboardService"
+	"walk-server/service/routeService"
+	"walk-server/service/sessionService"
 	"walk-server/service/teamService"
 	"walk-server/service/userService"
+	"walk-server/state"
 	"walk-server/utility"
 
 	"github.com/gin-gonic/gin"
@@ -51,6 +55,7 @@ func GetTeam(c *gin.Context) {
 		utility.ResponseError(c, "该队伍为其他路线")
 		return
 	}
+	middleware.AuditTarget(c, team.ID)
 
 	var persons []model.Person
 	global.DB.Where("team_id = ?", team.ID).Find(&persons)
@@ -66,7 +71,7 @@ func GetTeam(c *gin.Context) {
 			"contact": gin.H{
 				"qq":     member.Qq,
 				"wechat": member.Wechat,
-				"tel":    member.Tel,
+				"tel":    displayTel(user, member.Tel),
 			},
 			"walk_status": member.WalkStatus,
 		})
@@ -117,6 +122,7 @@ func BindTeam(c *gin.Context) {
 		utility.ResponseError(c, "该队伍为其他路线")
 		return
 	}
+	middleware.AuditTarget(c, team.ID)
 
 	_, err = teamService.GetTeamByCode(postForm.Code)
 	if err == nil {
@@ -126,43 +132,64 @@ func BindTeam(c *gin.Context) {
 		utility.ResponseError(c, "服务错误，请联系负责人")
 		return
 	}
-	var persons []model.Person
-	global.DB.Where("team_id = ?", team.ID).Find(&persons)
-	flag := true
-	num := uint(0)
-	for _, p := range persons {
-		if p.WalkStatus != 3 && p.WalkStatus != 4 {
-			flag = false
-			break
-		} else {
-			if p.WalkStatus == 3 {
+
+	var num uint
+	var before, after dashboardService.Counts
+	var beforeState, afterState teamState
+	err = teamService.Transaction(func(tx *gorm.DB) error {
+		num, before, after = 0, dashboardService.Counts{}, dashboardService.Counts{}
+
+		team, err = teamService.TxGetTeamByID(tx, postForm.TeamID)
+		if err != nil {
+			return err
+		}
+		persons, err := userService.TxGetUsersByTeamID(tx, team.ID)
+		if err != nil {
+			return err
+		}
+		before.Add(team, persons)
+		beforeState = snapshotTeam(team, persons)
+
+		for _, p := range persons {
+			if p.WalkStatus != state.WalkScanned && p.WalkStatus != state.WalkAbandoned {
+				return scanError("还有成员未确认状态")
+			}
+			if p.WalkStatus == state.WalkScanned {
 				num++
 			}
 		}
-	}
 
-	if !flag {
-		utility.ResponseError(c, "还有成员未确认状态")
-		return
-	}
+		if (team.Num+1)/2 > uint8(num) {
+			return scanError("团队人数不足，无法绑定")
+		}
 
-	if (team.Num+1)/2 > uint8(num) {
-		utility.ResponseError(c, "团队人数不足，无法绑定")
+		if err := team.Transit(state.TeamStarted); err != nil {
+			return scanError(err.Error())
+		}
+		team.Code = postForm.Code
+		team.Point = 0
+		team.StartNum = num
+		team.Time = time.Now()
+		if err := model.TxUpdateTeam(tx, team); err != nil {
+			return err
+		}
+		after.Add(team, persons)
+		afterState = snapshotTeam(team, persons)
+		return recordPassage(tx, team, user, num)
+	})
+	if err != nil {
+		responseScanError(c, err)
 		return
 	}
-
-	team.Code = postForm.Code
-	team.Point = 0
-	team.Status = 5
-	team.StartNum = num
-	team.Time = time.Now()
-	teamService.Update(*team)
+	middleware.AuditChange(c, beforeState, afterState)
+	publishScan("bind", team, user, num, before, after)
 	utility.ResponseSuccess(c, nil)
 }
 
 type TeamStatusForm struct {
 	CodeType uint   `json:"code_type" binding:"required"` //1团队码2签到码
 	Content  string `json:"content" binding:"required"`   //团队码为team_id，签到码为code
+	Override bool   `json:"override"`                     //负责人强制放行回退扫码
 }
 
 func UpdateTeamStatus(c *gin.Context) {
@@ -176,6 +203,10 @@ func UpdateTeamStatus(c *gin.Context) {
 
 	user, _ := adminService.GetAdminByJWT(c)
 	var team *model.Team
+	if postForm.Override && !user.HasRole(model.RoleSuper, model.RoleRouteLead) {
+		utility.ResponseError(c, "只有负责人可以强制放行")
+		return
+	}
 	if postForm.CodeType == 1 {
 		teamID, convErr := strconv.ParseUint(postForm.Content, 10, 32)
 		if convErr != nil {
@@ -200,65 +231,127 @@ func UpdateTeamStatus(c *gin.Context) {
 		utility.ResponseError(c, "该队伍为其他路线")
 		return
 	}
-	if team.Status == 1 {
-		utility.ResponseError(c, "团队起点未扫码")
-		return
-	} else if team.Status == 3 || team.Status == 4 {
-		utility.ResponseError(c, "团队已结束，有疑问请咨询管理员")
-		return
-	}
-	var persons []model.Person
-	global.DB.Where("team_id = ?", team.ID).Find(&persons)
-	num := uint(0)
-	for _, p := range persons {
-		if p.WalkStatus == 3 || p.WalkStatus == 2 {
-			num++
-		}
-	}
+	middleware.AuditTarget(c, team.ID)
 
-	if num == 0 {
-		team.Status = 3
-		team.Point = int8(constant.PointMap[team.Route])
-		teamService.Update(*team)
-		utility.ResponseSuccess(c, gin.H{
-			"progress_num": 0,
-		})
-		return
-	}
+	var num uint
+	var skipped int8
+	var changed []string
+	var before, after dashboardService.Counts
+	var beforeState, afterState teamState
+	err = teamService.Transaction(func(tx *gorm.DB) error {
+		num, skipped, changed = 0, 0, changed[:0]
+		before, after = dashboardService.Counts{}, dashboardService.Counts{}
 
-	// 各路线点位签到逻辑设置
-	switch team.Route {
-	case 2:
-		if user.Route == 3 && (user.Point == 2 || user.Point == 3 || user.Point == 4) {
-			utility.ResponseError(c, "该队伍为半程路线，让队伍继续往前走就行")
-			return
+		team, err = teamService.TxGetTeamByID(tx, team.ID)
+		if err != nil {
+			return err
 		}
-		if user.Point > 2 {
-			team.Point = user.Point - 2
-		} else {
-			team.Point = user.Point
+		if team.Status == state.TeamNotStarted {
+			return scanError("团队起点未扫码")
+		} else if team.Status.IsOver() {
+			return scanError("团队已结束，有疑问请咨询管理员")
 		}
-	case 3:
-		if user.Route == 2 && user.Point == 2 {
-			utility.ResponseError(c, "该队伍为全程路线，让队伍继续往前走就行")
-			return
+
+		persons, err := userService.TxGetUsersByTeamID(tx, team.ID)
+		if err != nil {
+			return err
+		}
+		before.Add(team, persons)
+		beforeState = snapshotTeam(team, persons)
+		for _, p := range persons {
+			if p.WalkStatus.IsWalking() {
+				num++
+			}
+		}
+
+		if num == 0 {
+			if err := team.Transit(state.TeamIncomplete); err != nil {
+				return scanError(err.Error())
+			}
+			team.Point = int8(constant.GetPointNum(team.Route))
+			team.Time = time.Now()
+			if err := model.TxUpdateTeam(tx, team); err != nil {
+				return err
+			}
+			after.Add(team, persons)
+			afterState = snapshotTeam(team, persons)
+			return recordPassage(tx, team, user, num)
 		}
-		team.Point = user.Point
-	default:
-		team.Point = user.Point
-	}
 
-	for _, p := range persons {
-		if p.WalkStatus == 3 {
-			p.WalkStatus = 2
-			userService.Update(p)
+		// 根据路线拓扑找到管理员所在地点对应的队伍点位
+		point, err := routeService.LocatePoint(user.Route, user.Point, team.Route)
+		if errors.Is(err, routeService.ErrPassBy) {
+			return scanError("该队伍为" + constant.GetRouteName(team.Route) + "路线，让队伍继续往前走就行")
+		} else if err != nil {
+			return scanError(err.Error())
+		}
+
+		// 校验点位顺序，回退扫码需要负责人强制放行，跳过点位记录为异常
+		var anomalyType uint8
+		skipped, err = routeService.CheckProgress(team.Point, point)
+		if errors.Is(err, routeService.ErrBackward) {
+			if !postForm.Override {
+				return scanError(err.Error())
+			}
+			anomalyType = 2
+		} else if skipped > 0 {
+			anomalyType = 1
+		}
+		lastPoint := team.Point
+		team.Point = point
+
+		for i := range persons {
+			p := &persons[i]
+			if p.WalkStatus == state.WalkScanned {
+				if err := p.TransitWalk(state.WalkInProgress); err != nil {
+					return scanError(err.Error())
+				}
+				if err := model.TxUpdatePersonWithVersion(tx, p); err != nil {
+					return err
+				}
+				changed = append(changed, p.OpenId)
+			}
+		}
+		if err := team.Transit(state.TeamInProgress); err != nil {
+			return scanError(err.Error())
+		}
+		team.Time = time.Now()
+		if err := model.TxUpdateTeam(tx, team); err != nil {
+			return err
 		}
+		after.Add(team, persons)
+		afterState = snapshotTeam(team, persons)
+		if err := recordPassage(tx, team, user, num); err != nil {
+			return err
+		}
+		if anomalyType != 0 {
+			return model.TxInsertAnomaly(tx, model.ScanAnomaly{
+				TeamID:    team.ID,
+				Route:     team.Route,
+				Type:      anomalyType,
+				FromPoint: lastPoint,
+				ToPoint:   team.Point,
+				AdminID:   user.ID,
+				Time:      team.Time,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		responseScanError(c, err)
+		return
 	}
-	team.Time = time.Now()
-	team.Status = 2
-	teamService.Update(*team)
+	model.ClearPersonCache(changed...)
+	middleware.AuditChange(c, beforeState, afterState)
+	if team.Status == state.TeamIncomplete {
+		publishScan("incomplete", team, user, num, before, after)
+	} else {
+		publishScan("scan", team, user, num, before, after)
+	}
+
 	utility.ResponseSuccess(c, gin.H{
 		"progress_num": num,
+		"skipped":      skipped,
 	})
 }
 
@@ -288,55 +381,90 @@ func PostDestination(c *gin.Context) {
 		utility.ResponseError(c, "该队伍为其他路线")
 		return
 	}
+	middleware.AuditTarget(c, team.ID)
 
-	var persons []model.Person
-	global.DB.Where("team_id = ?", team.ID).Find(&persons)
-	num := uint(0)
-	for _, p := range persons {
-		if p.WalkStatus == 3 || p.WalkStatus == 2 {
-			num++
-		}
-	}
+	var num uint
+	var changed []string
+	var before, after dashboardService.Counts
+	var beforeState, afterState teamState
+	err = teamService.Transaction(func(tx *gorm.DB) error {
+		num, changed = 0, changed[:0]
+		before, after = dashboardService.Counts{}, dashboardService.Counts{}
 
-	if team.Status == 4 || team.Status == 3 {
-		utility.ResponseError(c, "队伍状态已确认，有疑问请咨询管理员")
-		return
-	}
+		team, err = teamService.TxGetTeamByID(tx, postForm.TeamID)
+		if err != nil {
+			return err
+		}
+		if team.Status.IsOver() {
+			return scanError("队伍状态已确认，有疑问请咨询管理员")
+		}
 
-	if num == 0 {
-		team.Status = 3
-		team.Point = int8(constant.PointMap[team.Route])
-		teamService.Update(*team)
-		utility.ResponseSuccess(c, nil)
-		return
-	}
+		persons, err := userService.TxGetUsersByTeamID(tx, team.ID)
+		if err != nil {
+			return err
+		}
+		before.Add(team, persons)
+		beforeState = snapshotTeam(team, persons)
+		for _, p := range persons {
+			if p.WalkStatus.IsWalking() {
+				num++
+			}
+		}
 
-	team.Point = int8(constant.PointMap[team.Route])
-	team.Time = time.Now()
+		team.Point = int8(constant.GetPointNum(team.Route))
+		team.Time = time.Now()
+		if num == 0 || postForm.Status != 1 {
+			if err := team.Transit(state.TeamIncomplete); err != nil {
+				return scanError(err.Error())
+			}
+			if err := model.TxUpdateTeam(tx, team); err != nil {
+				return err
+			}
+			after.Add(team, persons)
+			afterState = snapshotTeam(team, persons)
+			return recordPassage(tx, team, user, num)
+		}
 
-	if postForm.Status == 1 {
-		for _, p := range persons {
-			if p.WalkStatus == 2 || p.WalkStatus == 3 {
-				p.WalkStatus = 5
-				userService.Update(p)
+		for i := range persons {
+			p := &persons[i]
+			if p.WalkStatus.IsWalking() {
+				if err := p.TransitWalk(state.WalkFinished); err != nil {
+					return scanError(err.Error())
+				}
+				if err := model.TxUpdatePersonWithVersion(tx, p); err != nil {
+					return err
+				}
+				changed = append(changed, p.OpenId)
 			}
 		}
-		team.Status = 4
-		teamService.Update(*team)
-		utility.ResponseSuccess(c, nil)
+		if err := team.Transit(state.TeamFinished); err != nil {
+			return scanError(err.Error())
+		}
+		if err := model.TxUpdateTeam(tx, team); err != nil {
+			return err
+		}
+		after.Add(team, persons)
+		afterState = snapshotTeam(team, persons)
+		return recordPassage(tx, team, user, num)
+	})
+	if err != nil {
+		responseScanError(c, err)
 		return
+	}
+	model.ClearPersonCache(changed...)
+	middleware.AuditChange(c, beforeState, afterState)
+	if team.Status == state.TeamFinished {
+		publishScan("finish", team, user, num, before, after)
 	} else {
-		team.Status = 3
-		teamService.Update(*team)
-		utility.ResponseSuccess(c, nil)
-		return
+		publishScan("incomplete", team, user, num, before, after)
 	}
+
+	utility.ResponseSuccess(c, nil)
 }
 
 type RegroupForm struct {
-	Jwts   []string `json:"jwts" binding:"required"`
-	Secret string   `json:"secret" binding:"required"`
-	Route  uint8    `json:"route" binding:"required"`
+	Jwts  []string `json:"jwts" binding:"required"`
+	Route uint8    `json:"route" binding:"required"`
 }
 
 func Regroup(c *gin.Context) {
@@ -347,8 +475,10 @@ func Regroup(c *gin.Context) {
 		utility.ResponseError(c, "参数错误")
 		return
 	}
-	if postForm.Secret != global.Config.GetString("server.secret") {
-		utility.ResponseError(c, "密码错误")
+
+	user, _ := adminService.GetAdminByJWT(c)
+	if !middleware.CheckRouteID(user, postForm.Route) {
+		utility.ResponseError(c, "没有该路线的权限")
 		return
 	}
 
@@ -357,16 +487,25 @@ func Regroup(c *gin.Context) {
 	for _, jwt := range postForm.Jwts {
 		if processedJwts[jwt] {
 			utility.ResponseError(c, "重复扫码,请重新提交")
+			return
 		}
 		processedJwts[jwt] = true
 
-		jwtToken := jwt[7:]
-		jwtData, err := utility.ParseToken(jwtToken)
-
+		jwtToken, ok := utility.TrimBearer(jwt)
+		if !ok {
+			utility.ResponseError(c, "扫码错误，请重新扫码")
+			return
+		}
+		jwtData, err := utility.ParseUserToken(jwtToken)
 		if err != nil {
 			utility.ResponseError(c, "扫码错误，请重新扫码")
 			return
 		}
+		// 参与者已退出登录的二维码不能再用于组队
+		if jwtData.Session == "" || sessionService.IsRevoked(jwtData.Session) {
+			utility.ResponseError(c, "二维码已失效，请重新扫码")
+			return
+		}
 
 		// 获取个人信息
 		person, err := model.GetPerson(jwtData.OpenID)
@@ -379,7 +518,7 @@ func Regroup(c *gin.Context) {
 		// 如果已有队伍则获取队伍信息
 		if person.TeamId != -1 {
 			team, _ := teamService.GetTeamByID(uint(person.TeamId))
-			if team.Status != 1 {
+			if team.Status != state.TeamNotStarted {
 				utility.ResponseError(c, person.Name+"的原队伍已开始，请勿重新组队")
 				return
 			}
@@ -388,20 +527,40 @@ func Regroup(c *gin.Context) {
 		persons = append(persons, *person)
 	}
 
+	before, after := dashboardService.Counts{}, dashboardService.Counts{}
+	beforeState := make(map[uint]teamState)
+	removed := make(map[int]bool)
 	for _, person := range persons {
-		// 如果已有队伍则退出
-		if person.TeamId != -1 {
-			captain, persons := model.GetPersonsInTeam(person.TeamId)
-			for _, p := range persons {
+		// 如果已有队伍则退出，同一队伍只处理一次
+		if person.TeamId != -1 && !removed[person.TeamId] {
+			removed[person.TeamId] = true
+			captain, members := model.GetPersonsInTeam(person.TeamId)
+			if captain.OpenId != "" {
+				members = append(members, captain)
+			}
+			oldTeam, oldErr := teamService.GetTeamByID(uint(person.TeamId))
+			if oldErr == nil {
+				before.Add(oldTeam, members)
+				beforeState[oldTeam.ID] = snapshotTeam(oldTeam, members)
+			}
+			for _, p := range members {
+				if err := p.TransitWalk(state.WalkNotStarted); err != nil {
+					utility.ResponseError(c, p.Name+"的"+err.Error())
+					return
+				}
 				p.TeamId = -1
 				p.Status = 0
-				p.WalkStatus = 1
-				userService.Update(p)
+				if err := userService.Update(p); err != nil {
+					utility.ResponseError(c, "服务错误")
+					return
+				}
+				if oldErr == nil {
+					if err := model.TxRecordMembership(global.DB, p.OpenId, oldTeam, model.MembershipLeft, "管理员重新组队"); err != nil {
+						utility.ResponseError(c, "服务错误")
+						return
+					}
+				}
 			}
-			captain.TeamId = -1
-			captain.Status = 0
-			captain.WalkStatus = 1
-			userService.Update(captain)
 			team, err := teamService.GetTeamByID(uint(person.TeamId))
 			if err == nil {
 				err = teamService.Delete(*team)
@@ -424,7 +583,7 @@ func Regroup(c *gin.Context) {
 		AllowMatch: true,
 		Slogan:     "新的开始",
 		Point:      -1,
-		Status:     1,
+		Status:     state.TeamNotStarted,
 		StartNum:   uint(0),
 		Num:        uint8(len(persons)),
 		Captain:    persons[0].OpenId,
@@ -444,16 +603,37 @@ func Regroup(c *gin.Context) {
 	}
 
 	// 更新每个人的队伍ID
-	for i, person := range persons {
+	for i := range persons {
+		person := &persons[i]
+		if err := person.TransitWalk(state.WalkNotStarted); err != nil {
+			utility.ResponseError(c, person.Name+"的"+err.Error())
+			return
+		}
 		person.TeamId = int(team.ID)
 		if i == 0 {
 			person.Status = 2
 		} else {
 			person.Status = 1
 		}
-		userService.Update(person)
+		if err := userService.Update(*person); err != nil {
+			utility.ResponseError(c, "服务错误")
+			return
+		}
+		if err := model.TxRecordMembership(global.DB, person.OpenId, team, model.MembershipJoined, "管理员重新组队"); err != nil {
+			utility.ResponseError(c, "服务错误")
+			return
+		}
+	}
+	global.Rdb.SAdd(global.Rctx, "teams", strconv.Itoa(int(team.ID)))
+	after.Add(team, persons)
+	dashboardService.Sync(before, after)
+
+	openIDs := make([]string, 0, len(persons))
+	for _, person := range persons {
+		openIDs = append(openIDs, person.OpenId)
 	}
-	global.Rdb.SAdd(global.Rctx, "teams", strconv.Itoa(int(newTeam.ID)))
+	middleware.AuditTarget(c, team.ID, openIDs...)
+	middleware.AuditChange(c, beforeState, map[uint]teamState{team.ID: snapshotTeam(team, persons)})
 
 	utility.ResponseSuccess(c, gin.H{
 		"team_id": newTeam.ID,
@@ -461,8 +641,7 @@ func Regroup(c *gin.Context) {
 }
 
 type SubmitTeamForm struct {
-	TeamID uint   `json:"team_id" binding:"required"`
-	Secret string `json:"secret" binding:"required"`
+	TeamID uint `json:"team_id" binding:"required"`
 }
 
 func SubmitTeam(c *gin.Context) {
@@ -473,25 +652,30 @@ func SubmitTeam(c *gin.Context) {
 		utility.ResponseError(c, "参数错误")
 		return
 	}
-	if postForm.Secret != global.Config.GetString("server.secret") {
-		utility.ResponseError(c, "密码错误")
-		return
-	}
+	user, _ := adminService.GetAdminByJWT(c)
 	team, err := teamService.GetTeamByID(postForm.TeamID)
 	if team == nil || err != nil {
 		utility.ResponseError(c, "队伍查找失败，请重新核对")
 		return
 	}
+	if !middleware.CheckRoute(user, team) {
+		utility.ResponseError(c, "该队伍为其他路线")
+		return
+	}
+	middleware.AuditTarget(c, team.ID)
 
-	team.Submit = true
-	teamService.Update(*team)
-	global.Rdb.SAdd(global.Rctx, "teams", strconv.Itoa(int(team.ID)))
-	utility.ResponseSuccess(c, nil)
+	captain, persons := model.GetPersonsInTeam(int(team.ID))
+	persons = append(persons, captain)
+	beforeState := snapshotTeam(team, persons)
 
-}
+	// 和参与者提交使用同一套流程，看板计数和入队申请在提交时一并处理
+	if err := teamService.AdminSubmit(team); err != nil {
+		utility.ResponseError(c, "服务错误")
+		return
+	}
+	middleware.AuditChange(c, beforeState, snapshotTeam(team, persons))
+	utility.ResponseSuccess(c, nil)
 
-type GetDetailForm struct {
-	Secret string `form:"secret" binding:"required"`
 }
 
 type RouteDetail struct {
@@ -499,126 +683,14 @@ type RouteDetail struct {
 	Label string `json:"label"`
 }
 
-// GetDetail 获取全部路线的点位信息
+// GetDetail 获取全部路线的点位信息，数据来自 Redis 中增量维护的看板计数
 func GetDetail(c *gin.Context) {
-	var postForm GetDetailForm
-	if err := c.ShouldBindQuery(&postForm); err != nil {
-		utility.ResponseError(c, "参数错误")
-		return
-	}
-	if postForm.Secret != global.Config.GetString("server.secret") {
-		utility.ResponseError(c, "密码错误")
+	data, err := dashboardDetail()
+	if err != nil {
+		utility.ResponseError(c, "服务错误")
 		return
 	}
-
-	routes := map[string]int{
-		"zh":      1,
-		"pfHalf":  2,
-		"pfAll":   3,
-		"mgsHalf": 4,
-		"mgsAll":  5,
-	}
-
-	resultMap := make(map[string][]int64)
-	for key, route := range routes {
-		resultMap[key] = make([]int64, constant.PointMap[uint8(route)]+3)
-	}
-
-	// 获取各点位人数
-	getPointCounts := func(route int, status []int, team_stuats []int, points []int64) {
-		var pointCounts []struct {
-			Point int64
-			Count int64
-		}
-		global.DB.Model(&model.Person{}).
-			Select("teams.point, count(*) as count").
-			Joins("JOIN teams ON people.team_id = teams.id").
-			Where("teams.route = ? AND people.walk_status IN ? AND teams.status IN ?", route, status, team_stuats).
-			Group("teams.point").
-			Order("teams.point").
-			Scan(&pointCounts)
-
-		for _, pointCount := range pointCounts {
-			if pointCount.Point >= 0 && int(pointCount.Point) < int(constant.PointMap[uint8(route)])+1 {
-				points[pointCount.Point+1] = pointCount.Count
-			}
-		}
-
-	}
-
-	// 获取各路线未开始人数
-	getStartCounts := func(route int, points *int64) {
-		global.DB.Model(&model.Person{}).
-			Select("count(*) as count").
-			Joins("JOIN teams ON people.team_id = teams.id").
-			Where("teams.route = ? AND people.walk_status = 1 And teams.submit = 1", route).
-			Pluck("count", points)
-	}
-
-	// 获取各路线已结束和下撤人数
-	appendEndCounts := func(route int, points []int64) {
-		var endCount5, endCount4 int64
-		global.DB.Model(&model.Person{}).
-			Select("count(*) as count").
-			Joins("JOIN teams ON people.team_id = teams.id").
-			Where("teams.route = ? AND people.walk_status = 5", route).
-			Pluck("count", &endCount5)
-		global.DB.Model(&model.Person{}).
-			Select("count(*) as count").
-			Joins("JOIN teams ON people.team_id = teams.id").
-			Where("teams.route = ? AND people.walk_status = 4", route).
-			Pluck("count", &endCount4)
-		points[len(points)-2] = endCount5
-		points[len(points)-1] = endCount4
-	}
-
-	// 状态：进行中、未开始、已结束
-	personStatusInProgress := []int{2, 3}
-	teamStatusInProgress := []int{2, 5}
-
-	for key, route := range routes {
-		getPointCounts(route, personStatusInProgress, teamStatusInProgress, resultMap[key])
-		getStartCounts(route, &resultMap[key][0])
-		appendEndCounts(route, resultMap[key])
-	}
-
-	processRoute := func(routeName string, routeID int) []RouteDetail {
-		details := make([]RouteDetail, len(resultMap[routeName]))
-		for i, count := range resultMap[routeName] {
-			label := ""
-			switch i {
-			case 0:
-				label = "未开始"
-			case len(resultMap[routeName]) - 2:
-				label = "已结束"
-			case len(resultMap[routeName]) - 1:
-				label = "下撤"
-			default:
-				label = constant.GetPointName(uint8(routeID), int8(i-1))
-			}
-			details[i] = RouteDetail{
-				Count: count,
-				Label: label,
-			}
-		}
-		return details
-	}
-
-	// 处理各路线的数据
-	zhDetails := processRoute("zh", 1)
-	pfAllDetails := processRoute("pfAll", 3)
-	pfHalfDetails := processRoute("pfHalf", 2)
-	mgsHalfDetails := processRoute("mgsHalf", 4)
-	mgsAllDetails := processRoute("mgsAll", 5)
-
-	// 返回结果
-	utility.ResponseSuccess(c, gin.H{
-		"zh":      zhDetails,
-		"pfAll":   pfAllDetails,
-		"pfHalf":  pfHalfDetails,
-		"mgsHalf": mgsHalfDetails,
-		"mgsAll":  mgsAllDetails,
-	})
+	utility.ResponseSuccess(c, data)
 }
 
 type Result struct {
@@ -628,43 +700,15 @@ type Result struct {
 	TotalNum int64
 }
 
-// 定义结果类型
-type Results struct {
-	Zh      []Result `json:"zh"`
-	PfHalf  []Result `json:"pfHalf"`
-	PfAll   []Result `json:"pfAll"`
-	MgsHalf []Result `json:"mgsHalf"`
-	MgsAll  []Result `json:"mgsAll"`
-}
-
-// GetSubmitDetail 获取已提交队伍信息
+// GetSubmitDetail 获取已提交队伍信息，只返回管理员有权限的路线
 func GetSubmitDetail(c *gin.Context) {
-	var postForm GetDetailForm
-	if err := c.ShouldBindQuery(&postForm); err != nil {
-		utility.ResponseError(c, "参数错误")
-		return
-	}
-	if postForm.Secret != global.Config.GetString("server.secret") {
-		utility.ResponseError(c, "密码错误")
-		return
-	}
+	admin, _ := adminService.GetAdminByJWT(c)
 
-	// 创建结果集合
-	var results Results
+	// 创建结果集合，按路线标识分组
+	results := make(map[string][]Result)
 	submit := 1
 
-	// 定义路线和队伍类型的映射
-	routes := []struct {
-		Name  string
-		Route int
-	}{
-		{"朝晖", 1},
-		{"屏峰半程", 2},
-		{"屏峰全程", 3},
-		{"莫干山半程", 4},
-		{"莫干山全程", 5},
-	}
-
+	// 定义队伍类型
 	teamTypes := []struct {
 		Type    int
 		Name    string
@@ -677,47 +721,18 @@ func GetSubmitDetail(c *gin.Context) {
 	}
 
 	// 获取各个路线的队伍数据
-	for _, r := range routes {
+	for _, r := range constant.GetRoutes() {
+		if !middleware.CheckRouteID(admin, r.ID) {
+			continue
+		}
 		for _, t := range teamTypes {
-			teamCount, totalCount := getTeamStats(r.Route, submit, t.Type, t.IsMixed)
-
-			switch r.Name {
-			case "朝晖":
-				results.Zh = append(results.Zh, Result{
-					Route:    r.Name,
-					TeamType: t.Name,
-					TeamNum:  teamCount,
-					TotalNum: totalCount,
-				})
-			case "屏峰半程":
-				results.PfHalf = append(results.PfHalf, Result{
-					Route:    r.Name,
-					TeamType: t.Name,
-					TeamNum:  teamCount,
-					TotalNum: totalCount,
-				})
-			case "屏峰全程":
-				results.PfAll = append(results.PfAll, Result{
-					Route:    r.Name,
-					TeamType: t.Name,
-					TeamNum:  teamCount,
-					TotalNum: totalCount,
-				})
-			case "莫干山半程":
-				results.MgsHalf = append(results.MgsHalf, Result{
-					Route:    r.Name,
-					TeamType: t.Name,
-					TeamNum:  teamCount,
-					TotalNum: totalCount,
-				})
-			case "莫干山全程":
-				results.MgsAll = append(results.MgsAll, Result{
-					Route:    r.Name,
-					TeamType: t.Name,
-					TeamNum:  teamCount,
-					TotalNum: totalCount,
-				})
-			}
+			teamCount, totalCount := getTeamStats(int(r.ID), submit, t.Type, t.IsMixed)
+			results[r.Code] = append(results[r.Code], Result{
+				Route:    r.Name,
+				TeamType: t.Name,
+				TeamNum:  teamCount,
+				TotalNum: totalCount,
+			})
 		}
 	}
 
@@ -769,8 +784,7 @@ func getTeamStats(route int, submit int, captainType int, hasStudent bool) (int6
 }
 
 type allTeamForm struct {
-	Secret string `form:"secret" binding:"required"`
-	TeamID uint   `form:"team_id" binding:"required"` // 团队码为team_id
+	TeamID uint `form:"team_id" binding:"required"` // 团队码为team_id
 }
 
 func GetTeamBySecret(c *gin.Context) {
@@ -780,16 +794,13 @@ func GetTeamBySecret(c *gin.Context) {
 		utility.ResponseError(c, "参数错误")
 		return
 	}
-	if postForm.Secret != global.Config.GetString("server.secret") {
-		utility.ResponseError(c, "密码错误")
-		return
-	}
 	var team *model.Team
 	team, err = teamService.GetTeamByID(postForm.TeamID)
 	if team == nil || err != nil {
 		utility.ResponseError(c, "队伍编号输入错误，队伍查找失败")
 		return
 	}
+	user, _ := adminService.GetAdminByJWT(c)
 
 	var persons []model.Person
 	global.DB.Where("team_id = ?", team.ID).Find(&persons)
@@ -806,7 +817,7 @@ func GetTeamBySecret(c *gin.Context) {
 			"contact": gin.H{
 				"qq":     member.Qq,
 				"wechat": member.Wechat,
-				"tel":    member.Tel,
+				"tel":    displayTel(user, member.Tel),
 			},
 			"walk_status": member.WalkStatus,
 		})
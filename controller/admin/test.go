@@ -9,12 +9,12 @@ import (
 	"time"
 	"walk-server/global"
 	"walk-server/model"
+	"walk-server/state"
 	"walk-server/utility"
 )
 
 type CreateTestTeamData struct {
-	Secret string `json:"secret" binding:"required"`
-	Num    int    `json:"num" binding:"required"` // 队伍数量
+	Num int `json:"num" binding:"required"` // 队伍数量
 }
 
 func CreateTestTeams(c *gin.Context) {
@@ -24,11 +24,6 @@ func CreateTestTeams(c *gin.Context) {
 		return
 	}
 
-	if postForm.Secret != global.Config.GetString("server.secret") {
-		utility.ResponseError(c, "密码错误")
-		return
-	}
-
 	err := global.DB.Transaction(func(tx *gorm.DB) error {
 		// 1. 生成队伍数据（不插入数据库）
 		var teams []model.Team
@@ -42,7 +37,7 @@ func CreateTestTeams(c *gin.Context) {
 				Captain:    "", // 先留空，后面填充
 				Route:      1,
 				Point:      0,
-				Status:     1,
+				Status:     state.TeamNotStarted,
 				StartNum:   0,
 				Submit:     false,
 				Time:       time.Now(),
@@ -76,7 +71,7 @@ func CreateTestTeams(c *gin.Context) {
 					JoinOp:     1,
 					TeamId:     int(team.ID), // 赋值 Team ID
 					Type:       1,
-					WalkStatus: 1,
+					WalkStatus: state.WalkNotStarted,
 				}
 				persons = append(persons, person)
 
@@ -110,22 +105,7 @@ func CreateTestTeams(c *gin.Context) {
 	utility.ResponseSuccess(c, nil)
 }
 
-type DeleteTestTeamData struct {
-	Secret string `json:"secret" binding:"required"`
-}
-
 func DeleteTestTeams(c *gin.Context) {
-	var postForm DeleteTestTeamData
-	if err := c.ShouldBind(&postForm); err != nil {
-		utility.ResponseError(c, "参数错误")
-		return
-	}
-
-	if postForm.Secret != global.Config.GetString("server.secret") {
-		utility.ResponseError(c, "密码错误")
-		return
-	}
-
 	// 开启事务
 	err := global.DB.Transaction(func(tx *gorm.DB) error {
 		// 1. 删除 Team
@@ -149,28 +129,30 @@ func DeleteTestTeams(c *gin.Context) {
 	utility.ResponseSuccess(c, nil)
 }
 
-type UpdateTestTeamData struct {
-	Secret string `json:"secret" binding:"required"`
-}
-
 func UpdateTestTeams(c *gin.Context) {
-	var postForm UpdateTestTeamData
-	if err := c.ShouldBind(&postForm); err != nil {
-		utility.ResponseError(c, "参数错误")
-		return
-	}
-
-	if postForm.Secret != global.Config.GetString("server.secret") {
-		utility.ResponseError(c, "密码错误")
-		return
-	}
-
+	var changed []string
 	err := global.DB.Transaction(func(tx *gorm.DB) error {
-		// 1. 更新所有测试人员的 WalkStatus 为 2
-		if err := tx.Model(&model.Person{}).
-			Where("open_id LIKE ?", "test%").
-			Update("walk_status", 2).Error; err != nil {
-			return err // 发生错误，回滚事务
+		changed = changed[:0]
+
+		// 1. 测试人员按状态机先确认到场再出发
+		var persons []model.Person
+		if err := tx.Where("open_id LIKE ?", "test%").Find(&persons).Error; err != nil {
+			return err
+		}
+		for i := range persons {
+			p := &persons[i]
+			if p.WalkStatus != state.WalkScanned {
+				if err := p.TransitWalk(state.WalkScanned); err != nil {
+					return err
+				}
+			}
+			if err := p.TransitWalk(state.WalkInProgress); err != nil {
+				return err
+			}
+			if err := model.TxUpdatePersonWithVersion(tx, p); err != nil {
+				return err // 发生错误，回滚事务
+			}
+			changed = append(changed, p.OpenId)
 		}
 
 		// 2. 查询所有测试队伍
@@ -179,14 +161,21 @@ func UpdateTestTeams(c *gin.Context) {
 			return err
 		}
 
-		// 3. 更新测试队伍状态和随机 Point
+		// 3. 更新测试队伍状态和随机 Point，未开始的队伍先经过起点扫码
 		for _, team := range teams {
-			team.Status = 2
+			if team.Status == state.TeamNotStarted {
+				if err := team.Transit(state.TeamStarted); err != nil {
+					return err
+				}
+			}
+			if err := team.Transit(state.TeamInProgress); err != nil {
+				return err
+			}
 			team.Time = time.Now().Add(time.Duration(-1*rand.Intn(60)) * time.Minute).Add(time.Duration(-1*rand.Intn(24)) * time.Hour)
 			team.Submit = true
 			team.Point = int8(rand.Intn(7)) // 生成 0 到 6 之间的随机数
 
-			if err := tx.Save(&team).Error; err != nil {
+			if err := model.TxUpdateTeam(tx, &team); err != nil {
 				return err // 发生错误，回滚事务
 			}
 		}
@@ -198,6 +187,7 @@ func UpdateTestTeams(c *gin.Context) {
 		utility.ResponseError(c, "更新失败："+err.Error())
 		return
 	}
+	model.ClearPersonCache(changed...)
 
 	utility.ResponseSuccess(c, nil)
 }
@@ -0,0 +1,108 @@
+package admin
+
+import (
+	"time"
+	"walk-server/constant"
+	"walk-server/middleware"
+	"walk-server/model"
+	"walk-server/service/adminService"
+	"walk-server/service/teamService"
+	"walk-server/utility"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// recordPassage 在扫码事务中记录队伍在当前点位的扫码
+func recordPassage(tx *gorm.DB, team *model.Team, admin *model.Admin, num uint) error {
+	return model.TxInsertPassage(tx, model.CheckpointPassage{
+		TeamID:  team.ID,
+		Route:   team.Route,
+		Point:   team.Point,
+		AdminID: admin.ID,
+		Num:     num,
+		Time:    team.Time,
+	})
+}
+
+type TimelineForm struct {
+	TeamID uint `form:"team_id" binding:"required"`
+}
+
+// GetTeamTimeline 获取队伍经过各点位的时间线和各路段用时
+func GetTeamTimeline(c *gin.Context) {
+	var postForm TimelineForm
+	if err := c.ShouldBindQuery(&postForm); err != nil {
+		utility.ResponseError(c, "参数错误")
+		return
+	}
+
+	user, _ := adminService.GetAdminByJWT(c)
+	team, err := teamService.GetTeamByID(postForm.TeamID)
+	if team == nil || err != nil {
+		utility.ResponseError(c, "队伍查找失败，请重新核对")
+		return
+	}
+
+	if !middleware.CheckRoute(user, team) {
+		utility.ResponseError(c, "该队伍为其他路线")
+		return
+	}
+
+	passages, err := model.GetPassages(team.ID)
+	if err != nil {
+		utility.ResponseError(c, "服务错误")
+		return
+	}
+
+	adminIDs := make([]uint, 0, len(passages))
+	for _, passage := range passages {
+		adminIDs = append(adminIDs, passage.AdminID)
+	}
+	admins, err := adminService.GetAdminsByIDs(adminIDs)
+	if err != nil {
+		utility.ResponseError(c, "服务错误")
+		return
+	}
+
+	timeline := make([]gin.H, 0, len(passages))
+	segments := make([]gin.H, 0, len(passages))
+	for i, passage := range passages {
+		timeline = append(timeline, gin.H{
+			"point":      passage.Point,
+			"location":   constant.GetPointName(passage.Route, passage.Point),
+			"num":        passage.Num,
+			"admin_id":   passage.AdminID,
+			"admin_name": admins[passage.AdminID].Name,
+			"time":       passage.Time.Format(time.DateTime),
+		})
+
+		if i == 0 {
+			continue
+		}
+		prev := passages[i-1]
+		segments = append(segments, gin.H{
+			"from":     constant.GetPointName(prev.Route, prev.Point),
+			"to":       constant.GetPointName(passage.Route, passage.Point),
+			"duration": int64(passage.Time.Sub(prev.Time).Seconds()), // 单位 s
+		})
+	}
+
+	var total int64
+	if len(passages) > 1 {
+		total = int64(passages[len(passages)-1].Time.Sub(passages[0].Time).Seconds())
+	}
+
+	utility.ResponseSuccess(c, gin.H{
+		"team": gin.H{
+			"id":     team.ID,
+			"name":   team.Name,
+			"route":  team.Route,
+			"point":  constant.GetPointName(team.Route, team.Point),
+			"status": team.Status,
+		},
+		"timeline": timeline,
+		"segments": segments,
+		"total":    total, // 单位 s
+	})
+}
@@ -9,11 +9,14 @@ import (
 	"walk-server/middleware"
 	"walk-server/model"
 	"walk-server/service/adminService"
+	"walk-server/service/dashboardService"
 	"walk-server/service/teamService"
 	"walk-server/service/userService"
+	"walk-server/state"
 	"walk-server/utility"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type UserStatusForm struct {
@@ -36,65 +39,117 @@ func UserStatus(c *gin.Context) {
 	// 获取管理员信息
 	user, _ := adminService.GetAdminByJWT(c)
 
-	// 批量获取用户和队伍信息
-	users, teams, err := getUsersAndTeams(postForm.List)
-	if err != nil {
-		utility.ResponseError(c, err.Error())
-		return
-	}
+	var changed []string
+	var users map[string]*model.Person
+	var teams map[int]*model.Team
+	var before, after dashboardService.Counts
+	var beforeState, afterState map[uint]teamState
+	err := teamService.Transaction(func(tx *gorm.DB) error {
+		changed = changed[:0]
+		before, after = dashboardService.Counts{}, dashboardService.Counts{}
+		beforeState, afterState = make(map[uint]teamState), make(map[uint]teamState)
+
+		// 批量获取用户和队伍信息
+		var err error
+		users, teams, err = getUsersAndTeams(tx, postForm.List)
+		if err != nil {
+			return err
+		}
 
-	// 验证用户权限
-	for _, person := range users {
-		team, exists := teams[person.TeamId]
-		if !exists {
-			utility.ResponseError(c, "队伍信息获取失败")
-			return
+		// 验证用户权限
+		for _, person := range users {
+			team, exists := teams[person.TeamId]
+			if !exists {
+				return scanError("队伍信息获取失败")
+			}
+
+			// 管理员只能管理自己所在的校区
+			if !middleware.CheckRoute(user, team) {
+				return scanError("该队伍为其他路线")
+			}
+
+			// 验证毅行状态
+			if person.WalkStatus == state.WalkFinished {
+				return scanError("成员已结束毅行")
+			}
 		}
 
-		// 管理员只能管理自己所在的校区
-		if !middleware.CheckRoute(user, &team) {
-			utility.ResponseError(c, "该队伍为其他路线")
-			return
+		for _, team := range teams {
+			persons, err := userService.TxGetUsersByTeamID(tx, team.ID)
+			if err != nil {
+				return err
+			}
+			before.Add(team, persons)
+			beforeState[team.ID] = snapshotTeam(team, persons)
+		}
+
+		// 更新用户状态
+		for _, form := range postForm.List {
+			person := users[form.UserID]
+			next := state.WalkAbandoned
+			if form.Status == 1 {
+				next = state.WalkScanned
+			}
+			if err := person.TransitWalk(next); err != nil {
+				return scanError(person.Name + "的" + err.Error())
+			}
+			if err := model.TxUpdatePersonWithVersion(tx, person); err != nil {
+				return err
+			}
+			changed = append(changed, person.OpenId)
 		}
 
-		// 验证毅行状态
-		if person.WalkStatus == 5 {
-			utility.ResponseError(c, "成员已结束毅行")
-			return
+		// 检查队伍是否已经没人在行
+		for _, team := range teams {
+			persons, err := userService.TxGetUsersByTeamID(tx, team.ID)
+			if err != nil {
+				return err
+			}
+			num := 0
+			for _, person := range persons {
+				if person.WalkStatus != state.WalkAbandoned {
+					num++
+				}
+			}
+			if num == 0 && !team.Status.IsOver() {
+				if err := team.Transit(state.TeamIncomplete); err != nil {
+					return scanError(err.Error())
+				}
+				if err := model.TxUpdateTeam(tx, team); err != nil {
+					return err
+				}
+			}
+			after.Add(team, persons)
+			afterState[team.ID] = snapshotTeam(team, persons)
 		}
+		return nil
+	})
+	if err != nil {
+		responseScanError(c, err)
+		return
 	}
+	model.ClearPersonCache(changed...)
 
-	// 更新用户状态
+	// 按队伍推送确认到场和下撤事件
+	dashboardService.Sync(before, after)
+	confirmed := make(map[int]uint)
+	abandoned := make(map[int]uint)
+	middleware.AuditChange(c, beforeState, afterState)
 	for _, form := range postForm.List {
-		person := users[form.UserID]
+		teamID := users[form.UserID].TeamId
+		middleware.AuditTarget(c, uint(teamID), form.UserID)
 		if form.Status == 1 {
-			person.WalkStatus = 3
+			confirmed[teamID]++
 		} else {
-			person.WalkStatus = 4
+			abandoned[teamID]++
 		}
-		userService.Update(*person)
 	}
-
-	// 检查队伍是否已经没人在行
-	for _, user := range users {
-		num := 0
-		team, exists := teams[user.TeamId]
-		if !exists {
-			continue
+	for teamID, team := range teams {
+		if confirmed[teamID] > 0 {
+			publishEvent("confirm", team, user, confirmed[teamID])
 		}
-		persons, err := userService.GetUsersByTeamID(team.ID)
-		if err != nil {
-			utility.ResponseError(c, "获取队伍成员失败")
-			return
-		}
-		for _, person := range persons {
-			if person.WalkStatus != 4 {
-				num++
-			}
-		}
-		if num == 0 {
-			team.Status = 3
-			teamService.Update(team)
+		if abandoned[teamID] > 0 {
+			publishEvent("abandon", team, user, abandoned[teamID])
 		}
 	}
 
@@ -102,21 +157,26 @@ func UserStatus(c *gin.Context) {
 }
 
 // getUsersAndTeams retrieves user and team data for the given user IDs
-func getUsersAndTeams(forms []UserStatusForm) (map[string]*model.Person, map[int]model.Team, error) {
+func getUsersAndTeams(tx *gorm.DB, forms []UserStatusForm) (map[string]*model.Person, map[int]*model.Team, error) {
 	userMap := make(map[string]*model.Person)
-	teamMap := make(map[int]model.Team)
+	teamMap := make(map[int]*model.Team)
 
 	for _, form := range forms {
-		person, err := model.GetPerson(form.UserID)
-		if err != nil {
-			return nil, nil, errors.New("扫码错误，查找用户失败，请再次核对")
+		if _, exists := userMap[form.UserID]; exists {
+			continue
+		}
+		person, err := userService.TxGetUserByOpenID(tx, form.UserID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, scanError("扫码错误，查找用户失败，请再次核对")
+		} else if err != nil {
+			return nil, nil, err
 		}
 		userMap[form.UserID] = person
 
 		if _, exists := teamMap[person.TeamId]; !exists {
-			var team model.Team
-			if err := global.DB.Where("id = ?", person.TeamId).Take(&team).Error; err != nil {
-				return nil, nil, errors.New("队伍信息获取失败")
+			team, err := teamService.TxGetTeamByID(tx, uint(person.TeamId))
+			if err != nil {
+				return nil, nil, scanError("队伍信息获取失败")
 			}
 			teamMap[person.TeamId] = team
 		}
@@ -126,27 +186,26 @@ func getUsersAndTeams(forms []UserStatusForm) (map[string]*model.Person, map[int
 }
 
 type GetTimeoutUsersData struct {
-	Minute int    `form:"minute" binding:"required"` // 超时时间
-	Route  uint8  `form:"route" binding:"required"`  // 路线
-	Type   uint8  `form:"type"`                      // 类型
-	Secret string `form:"secret" binding:"required"` // 密钥
+	Minute int   `form:"minute" binding:"required"` // 超时时间
+	Route  uint8 `form:"route" binding:"required"`  // 路线
+	Type   uint8 `form:"type"`                      // 类型
 }
 
 type User struct {
-	Name       string    `json:"name"`
-	Gender     int8      `json:"gender"` // 1 男，2 女
-	StuId      string    `json:"stu_id"`
-	Campus     uint8     `json:"campus"`  // 1 朝晖，2 屏峰，3 莫干山
-	College    string    `json:"college"` // 学院
-	Tel        string    `json:"tel"`
-	Type       uint8     `json:"type"` // 1 学生， 2 教职工
-	Time       time.Time `json:"time"`
-	Point      int8      `json:"point"`
-	TeamID     uint      `json:"team_id"`
-	TeamName   string    `json:"team_name"`
-	Status     uint8     `json:"status"`      // 1 队员，2 队长
-	WalkStatus uint8     `json:"walk_status"` // 1 未开始，2 进行中，3 扫码成功，4 放弃，5 完成
-	Location   string    `json:"location"`
+	Name       string           `json:"name"`
+	Gender     int8             `json:"gender"` // 1 男，2 女
+	StuId      string           `json:"stu_id"`
+	Campus     uint8            `json:"campus"`  // 1 朝晖，2 屏峰，3 莫干山
+	College    string           `json:"college"` // 学院
+	Tel        string           `json:"tel"`
+	Type       uint8            `json:"type"` // 1 学生， 2 教职工
+	Time       time.Time        `json:"time"`
+	Point      int8             `json:"point"`
+	TeamID     uint             `json:"team_id"`
+	TeamName   string           `json:"team_name"`
+	Status     uint8            `json:"status"`      // 1 队员，2 队长
+	WalkStatus state.WalkStatus `json:"walk_status"` // 1 未开始，2 进行中，3 扫码成功，4 放弃，5 完成
+	Location   string           `json:"location"`
 }
 
 type PointUsers struct {
@@ -161,10 +220,12 @@ func GetTimeoutUsers(c *gin.Context) {
 		utility.ResponseError(c, "参数错误")
 		return
 	}
-	if postForm.Secret != global.Config.GetString("server.secret") {
-		utility.ResponseError(c, "密码错误")
+	admin, _ := adminService.GetAdminByJWT(c)
+	if !middleware.CheckRouteID(admin, postForm.Route) {
+		utility.ResponseError(c, "没有权限")
 		return
 	}
+	full := canViewPrivacy(admin)
 
 	// 获取超时队伍和成员信息（一次性查询）
 	type TeamWithMembers struct {
@@ -189,7 +250,7 @@ func GetTimeoutUsers(c *gin.Context) {
 
 	// 获取未到队伍
 	var noShowTeamsWithMembers []TeamWithMembers
-	noShowQuery := query.Where("teams.status = 1 AND teams.submit = 1")
+	noShowQuery := query.Where("teams.status = ? AND teams.submit = 1", state.TeamNotStarted)
 	if err := noShowQuery.Find(&noShowTeamsWithMembers).Error; err != nil {
 		utility.ResponseError(c, "获取失败，请稍后重试")
 		return
@@ -203,6 +264,9 @@ func GetTimeoutUsers(c *gin.Context) {
 	// 处理超时队伍
 	for _, tm := range teamsWithMembers {
 		user := buildUserData(tm.Person, tm.Team)
+		if !full {
+			maskUser(&user)
+		}
 		if postForm.Type == 0 || user.Type == postForm.Type {
 			teamMap[tm.Point] = append(teamMap[tm.Point], user)
 		}
@@ -211,6 +275,9 @@ func GetTimeoutUsers(c *gin.Context) {
 	// 处理未到队伍
 	for _, tm := range noShowTeamsWithMembers {
 		user := buildUserData(tm.Person, tm.Team)
+		if !full {
+			maskUser(&user)
+		}
 		if postForm.Type == 0 || user.Type == postForm.Type {
 			noShowUsers = append(noShowUsers, user)
 		}
@@ -248,10 +315,12 @@ func DownloadTimeoutUsers(c *gin.Context) {
 		utility.ResponseError(c, "参数错误")
 		return
 	}
-	if postForm.Secret != global.Config.GetString("server.secret") {
-		utility.ResponseError(c, "密码错误")
+	admin, _ := adminService.GetAdminByJWT(c)
+	if !middleware.CheckRouteID(admin, postForm.Route) {
+		utility.ResponseError(c, "没有权限")
 		return
 	}
+	full := canViewPrivacy(admin)
 
 	// 获取超时队伍
 	teamMap, err := adminService.GetTimeoutTeams(postForm.Minute, postForm.Route)
@@ -266,11 +335,17 @@ func DownloadTimeoutUsers(c *gin.Context) {
 		campusMap     = map[uint8]string{1: "朝晖", 2: "屏峰", 3: "莫干山"}
 		typeMap       = map[uint8]string{1: "学生", 2: "教职工", 3: "校友"}
 		statusMap     = map[uint8]string{1: "队员", 2: "队长"}
-		walkStatusMap = map[uint8]string{1: "未开始", 2: "进行中", 3: "进行中", 4: "放弃", 5: "已完成"}
+		walkStatusMap = map[state.WalkStatus]string{
+			state.WalkNotStarted: "未开始",
+			state.WalkInProgress: "进行中",
+			state.WalkScanned:    "进行中",
+			state.WalkAbandoned:  "放弃",
+			state.WalkFinished:   "已完成",
+		}
 	)
 
 	// 预分配切片容量
-	point := constant.PointMap[postForm.Route]
+	point := constant.GetPointNum(postForm.Route)
 	headers := []string{"上个点位", "上个点位签到时间", "队伍编号", "队伍名称", "姓名", "队伍担当", "当前状态", "性别", "学号", "电话", "校区", "学院", "参与者类型"}
 
 	// 使用 map 预分配容量
@@ -305,6 +380,9 @@ func DownloadTimeoutUsers(c *gin.Context) {
 
 			// 直接构建行数据并添加到对应点位的切片中
 			for _, user := range filteredUsers {
+				if !full {
+					maskUser(&user)
+				}
 				row := []any{
 					pointName,                       // 上个点位
 					user.Time.Format(time.DateTime), // 到达上个点位时间
@@ -326,10 +404,11 @@ func DownloadTimeoutUsers(c *gin.Context) {
 	}
 
 	// 构建工作表
+	sheetNames := utility.SheetNames(points)
 	sheets := make([]utility.Sheet, 0, len(points))
-	for _, point := range points {
+	for i, point := range points {
 		sheets = append(sheets, utility.Sheet{
-			Name:    point,
+			Name:    sheetNames[i],
 			Headers: headers,
 			Rows:    pointUserMap[point],
 		})
@@ -340,10 +419,8 @@ func DownloadTimeoutUsers(c *gin.Context) {
 	}
 
 	// 保存为 Excel 文件
-	fileName := constant.RouteMap[postForm.Route] + "路线未到人员名单.xlsx"
-	filePath := "./file/"
-	host := global.Config.GetString("frontend.url")
-	url, err := utility.CreateExcelFile(data, fileName, filePath, host)
+	fileName := constant.GetRouteName(postForm.Route) + "路线未到人员名单.xlsx"
+	url, err := utility.CreateExcelFile(data, fileName)
 	if err != nil {
 		utility.ResponseError(c, "生成文件失败")
 		return
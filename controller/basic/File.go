@@ -0,0 +1,29 @@
+package basic
+
+import (
+	"errors"
+	"os"
+	"walk-server/utility"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DownloadFile 通过签名链接下载生成的文件
+func DownloadFile(ctx *gin.Context) {
+	name := ctx.Query("name")
+	path, err := utility.VerifyFileLink(ctx.Param("id"), name, ctx.Query("expires"), ctx.Query("sign"))
+	if errors.Is(err, utility.ErrFileLinkInvalid) || errors.Is(err, utility.ErrFileLinkExpired) {
+		utility.ResponseError(ctx, err.Error())
+		return
+	} else if err != nil {
+		utility.ResponseError(ctx, "服务错误")
+		return
+	}
+	if _, err := os.Stat(path); err != nil {
+		utility.ResponseError(ctx, "文件不存在或已被清理")
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.FileAttachment(path, name)
+}
@@ -8,6 +8,7 @@ import (
 	"net/url"
 	"strings"
 	"walk-server/global"
+	"walk-server/service/sessionService"
 	"walk-server/service/userService"
 	"walk-server/utility"
 
@@ -15,7 +16,6 @@ import (
 )
 
 func Login(ctx *gin.Context) {
-	var jwtData utility.JwtData
 	code := ctx.Query("code") // 微信回调的 code 参数
 
 	if code == "" {
@@ -31,27 +31,48 @@ func Login(ctx *gin.Context) {
 		utility.ResponseError(ctx, "请在微信中打开")
 		return
 	}
-	jwtData.OpenID = utility.AesEncrypt(openID, global.Config.GetString("server.AESSecret"))
-
-	// 生成 JWT
-	urlToken, err := utility.UrlToken(&jwtData)
+	// 只把一次性的登录票据放在跳转链接中，前端通过 LoginByTicket 兑换登录凭证
+	ticket, err := sessionService.CreateTicket(utility.AesEncrypt(openID, global.Config.GetString("server.AESSecret")))
 	if err != nil {
 		utility.ResponseError(ctx, "登录错误，请重新打开网页重试")
 		return
 	}
 
-	// 如果在调试模式下就输出用户的 jwt token
+	// 如果在调试模式下就输出用户的登录票据
 	if utility.IsDebugMode() {
-		fmt.Printf("[Debug Info] %v\n", urlToken)
+		fmt.Printf("[Debug Info] %v\n", ticket)
 	}
 
 	frontEndUrl := global.Config.GetString("frontEnd.url")
-	redirectUrl := frontEndUrl + "?jwt=" + urlToken
+	redirectUrl := frontEndUrl + "?ticket=" + url.QueryEscape(ticket)
 	ctx.Redirect(http.StatusTemporaryRedirect, redirectUrl)
 }
 
+type LoginByTicketForm struct {
+	Ticket string `json:"ticket" binding:"required"`
+}
+
+// LoginByTicket 使用微信回调生成的一次性票据换取登录凭证
+func LoginByTicket(ctx *gin.Context) {
+	var postForm LoginByTicketForm
+	if err := ctx.ShouldBindJSON(&postForm); err != nil {
+		utility.ResponseError(ctx, "参数错误")
+		return
+	}
+
+	openID, err := sessionService.ExchangeTicket(postForm.Ticket)
+	if errors.Is(err, sessionService.ErrTicketInvalid) {
+		utility.ResponseError(ctx, "登录已过期，请重新打开网页")
+		return
+	} else if err != nil {
+		utility.ResponseError(ctx, "登录错误，请重新打开网页重试")
+		return
+	}
+	loginResponse(ctx, openID)
+}
+
+// LoginByOpenID 通过 openID 直接登录，没有校验 openID 的归属，只在调试模式下开放
 func LoginByOpenID(ctx *gin.Context) {
-	var jwtData utility.JwtData
 	openID := ctx.DefaultQuery("open_id", "")
 	if openID == "" {
 		utility.ResponseError(ctx, "openID 为空")
@@ -66,16 +87,19 @@ func LoginByOpenID(ctx *gin.Context) {
 	// 如果解码后的字符串中有空格，可以恢复为 "+"
 	decodedOpenID = strings.Replace(decodedOpenID, " ", "+", -1)
 
-	user, err := userService.GetUserByOpenID(decodedOpenID)
+	loginResponse(ctx, decodedOpenID)
+}
+
+// loginResponse 创建登录会话，返回登录凭证和打码后的用户信息
+func loginResponse(ctx *gin.Context, openID string) {
+	user, err := userService.GetUserByOpenID(openID)
 	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
 		utility.ResponseError(ctx, "获取用户信息失败")
 		return
 	}
 
-	jwtData.OpenID = decodedOpenID
-
 	// 生成 JWT
-	urlToken, err := utility.GenerateStandardJwt(&jwtData)
+	jwtToken, refreshToken, err := sessionService.Create(ctx, utility.NewUserClaims(openID))
 	if err != nil {
 		utility.ResponseError(ctx, "登录错误，请重新打开网页重试")
 		return
@@ -83,11 +107,12 @@ func LoginByOpenID(ctx *gin.Context) {
 
 	// 如果在调试模式下就输出用户的 jwt token
 	if utility.IsDebugMode() {
-		fmt.Printf("[Debug Info] %v\n", urlToken)
+		fmt.Printf("[Debug Info] %v\n", jwtToken)
 	}
 
 	utility.ResponseSuccess(ctx, gin.H{
-		"jwt":  urlToken,
-		"user": user,
+		"jwt":           jwtToken,
+		"refresh_token": refreshToken,
+		"user":          user.Masked(),
 	})
 }
@@ -0,0 +1,35 @@
+package basic
+
+import (
+	"errors"
+	"walk-server/service/sessionService"
+	"walk-server/utility"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RefreshTokenForm struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// RefreshToken 参与者使用 refresh token 换取新的 access token，旧的 refresh token 随即失效
+func RefreshToken(ctx *gin.Context) {
+	var postForm RefreshTokenForm
+	if err := ctx.ShouldBindJSON(&postForm); err != nil {
+		utility.ResponseError(ctx, "参数错误")
+		return
+	}
+
+	jwtToken, refreshToken, err := sessionService.Refresh(postForm.RefreshToken, utility.KindUser)
+	if errors.Is(err, sessionService.ErrSessionExpired) || errors.Is(err, sessionService.ErrRefreshReused) {
+		utility.ResponseError(ctx, "登录已失效，请重新登录")
+		return
+	} else if err != nil {
+		utility.ResponseError(ctx, "服务错误")
+		return
+	}
+	utility.ResponseSuccess(ctx, gin.H{
+		"jwt":           jwtToken,
+		"refresh_token": refreshToken,
+	})
+}
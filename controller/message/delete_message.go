@@ -12,8 +12,7 @@ type DeleteMessageData struct {
 
 func DeleteMessage(context *gin.Context) {
 	// 获取 jwt 数据
-	jwtToken := context.GetHeader("Authorization")[7:]
-	jwtData, _ := utility.ParseToken(jwtToken)
+	jwtData := utility.GetJwtData(context)
 
 	// 获取消息 ID
 	var deleteMessageData DeleteMessageData
@@ -10,8 +10,7 @@ import (
 // ListMessage 获取自己应该接收的邮件
 func ListMessage(context *gin.Context) {
 	// 获取 jwt 数据
-	jwtToken := context.GetHeader("Authorization")[7:]
-	jwtData, _ := utility.ParseToken(jwtToken)
+	jwtData := utility.GetJwtData(context)
 
 	messages, err := model.GetMessages(jwtData.OpenID)
 	if err != nil {
@@ -1,6 +1,7 @@
 package poster
 
 import (
+	"walk-server/constant"
 	"walk-server/model"
 	"walk-server/utility"
 
@@ -16,12 +17,8 @@ var teamRouteMap = map[uint8]string{
 }
 
 func GetPoster(context *gin.Context) {
-	// 获取 jwt 数据
-	jwtToken := context.GetHeader("Authorization")[7:]
-	jwtData, _ := utility.ParseToken(jwtToken)
-
 	// 获取团队信息
-	user, _ := model.GetPerson(jwtData.OpenID)
+	user := utility.GetPerson(context)
 	team, err := model.GetTeamInfo(uint(user.TeamId))
 	if err != nil {
 		utility.ResponseError(context, "no team")
@@ -36,7 +33,12 @@ func GetPoster(context *gin.Context) {
 		memberNames = append(memberNames, member.Name)
 	}
 
-	imgUrl, err := utility.Poster(teamRouteMap[team.Route], team.Name, team.Slogan, int(team.Num), memberNames)
+	routeName, ok := teamRouteMap[team.Route]
+	if !ok {
+		routeName = constant.GetRouteName(team.Route)
+	}
+
+	imgUrl, err := utility.Poster(routeName, team.Name, team.Slogan, int(team.Num), memberNames)
 	if err != nil {
 		utility.ResponseError(context, "海报生成错误")
 		return
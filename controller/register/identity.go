@@ -0,0 +1,35 @@
+package register
+
+import (
+	"errors"
+	"walk-server/service/identityService"
+	"walk-server/utility"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 可以直接提示给用户的身份验证错误
+var identityErrors = []error{
+	identityService.ErrWrongCredential,
+	identityService.ErrClosed,
+	identityService.ErrNotActivated,
+	identityService.ErrVerifyFailed,
+	identityService.ErrNotInRoster,
+	identityService.ErrNoProvider,
+}
+
+// verifyIdentity 使用该类人员配置的身份验证方式验证报名者身份
+func verifyIdentity(context *gin.Context, cred identityService.Credential) (*identityService.Identity, bool) {
+	identity, err := identityService.Verify(cred)
+	if err != nil {
+		for _, e := range identityErrors {
+			if errors.Is(err, e) {
+				utility.ResponseError(context, e.Error())
+				return nil, false
+			}
+		}
+		utility.ResponseError(context, "系统错误，请稍后再试")
+		return nil, false
+	}
+	return identity, true
+}
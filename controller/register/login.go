@@ -15,8 +15,7 @@ type LoginData struct {
 
 func Login(context *gin.Context) {
 	// 获取 openID
-	jwtToken := context.GetHeader("Authorization")[7:]
-	jwtData, _ := utility.ParseToken(jwtToken) // 中间件校验过是否合法了
+	jwtData := utility.GetJwtData(context) // 中间件校验过是否合法了
 
 	var postForm LoginData
 	err := context.ShouldBindJSON(&postForm)
@@ -25,7 +24,7 @@ func Login(context *gin.Context) {
 		utility.ResponseError(context, "参数错误")
 		return
 	}
-	user, err := userService.GetUserByID(postForm.ID)
+	user, err := userService.GetUserByID(utility.NormalizeIdentity(postForm.ID))
 	if err != nil {
 		utility.ResponseError(context, "信息错误,请检查是否填写有误")
 		return
@@ -1,11 +1,11 @@
 package register
 
 import (
-	"errors"
-	"github.com/zjutjh/WeJH-SDK/oauth"
-	"github.com/zjutjh/WeJH-SDK/oauth/oauthException"
 	"walk-server/global"
 	"walk-server/model"
+	"walk-server/privacy"
+	"walk-server/service/identityService"
+	"walk-server/state"
 	"walk-server/utility"
 
 	"github.com/gin-gonic/gin"
@@ -26,8 +26,7 @@ type TeacherRegisterData struct {
 
 func TeacherRegister(context *gin.Context) {
 	// 获取 openID
-	jwtToken := context.GetHeader("Authorization")[7:]
-	jwtData, _ := utility.ParseToken(jwtToken) // 中间件校验过是否合法了
+	jwtData := utility.GetJwtData(context) // 中间件校验过是否合法了
 
 	// 获取报名数据
 	var postData TeacherRegisterData
@@ -38,52 +37,33 @@ func TeacherRegister(context *gin.Context) {
 	}
 
 	var user model.Person
-	result := global.DB.Where("stu_id =? Or identity = ? Or tel = ?", postData.StuID, postData.ID, postData.Contact.Tel).Take(&user)
+	postData.ID = utility.NormalizeIdentity(postData.ID)
+	result := global.DB.Where("stu_id =? Or identity_index = ? Or tel = ?", postData.StuID, privacy.BlindIndex(postData.ID), postData.Contact.Tel).Take(&user)
 	if result.RowsAffected != 0 {
 		utility.ResponseError(context, "已有身份信息，请检查是否填写错误")
 		return
 	}
 
-	cookie, info, err := oauth.GetUserInfo(postData.StuID, postData.Password)
-	var oauthErr *oauthException.Error
-	if errors.As(err, &oauthErr) {
-		if errors.Is(oauthErr, oauthException.WrongAccount) || errors.Is(oauthErr, oauthException.WrongPassword) {
-			utility.ResponseError(context, "账号或密码错误")
-			return
-		} else if errors.Is(oauthErr, oauthException.ClosedError) {
-			utility.ResponseError(context, "统一夜间关闭，请白天尝试")
-			return
-		} else if errors.Is(oauthErr, oauthException.NotActivatedError) {
-			utility.ResponseError(context, "账号未激活，请自行到统一网站重新激活")
-			return
-		} else {
-			utility.ResponseError(context, "系统错误，请稍后再试")
-			return
-		}
-	} else if err != nil {
-		utility.ResponseError(context, "系统错误，请稍后再试")
+	info, ok := verifyIdentity(context, identityService.Credential{
+		Type:     identityService.TypeTeacher,
+		Account:  postData.StuID,
+		Password: postData.Password,
+		Identity: postData.ID,
+	})
+	if !ok {
 		return
 	}
-	if cookie == nil {
-		utility.ResponseError(context, "统一验证失败，请稍后再试")
-		return
-	}
-	if info.UserTypeDesc != "教师职工" && info.UserTypeDesc != "人才派遣" {
+	// 名单中的校友和教职工使用同一个报名入口
+	if info.Type != identityService.TypeTeacher && info.Type != identityService.TypeAlumnus {
 		utility.ResponseError(context, "您不是教师职工，请返回学生注册")
 		return
 	}
-	var gender int8
-	if info.Gender == "male" {
-		gender = 1
-	} else {
-		gender = 2
-	}
 
 	person := model.Person{
 		OpenId:     jwtData.OpenID,
 		StuId:      postData.StuID,
 		Name:       info.Name,
-		Gender:     gender,
+		Gender:     info.Gender,
 		Identity:   postData.ID,
 		College:    info.College,
 		Status:     0,
@@ -93,8 +73,8 @@ func TeacherRegister(context *gin.Context) {
 		CreatedOp:  2,
 		JoinOp:     5,
 		TeamId:     -1,
-		WalkStatus: 1,
-		Type:       2,
+		WalkStatus: state.WalkNotStarted,
+		Type:       info.Type,
 	}
 
 	result = global.DB.Create(&person)
@@ -3,7 +3,6 @@ package team
 import (
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
-	"strconv"
 	"walk-server/global"
 	"walk-server/model"
 	"walk-server/utility"
@@ -15,12 +14,8 @@ type request struct {
 }
 
 func ChangeCaptain(context *gin.Context) {
-	// 获取 jwt 数据
-	jwtToken := context.GetHeader("Authorization")[7:]
-	jwtData, _ := utility.ParseToken(jwtToken)
-
 	// 查找用户
-	person, _ := model.GetPerson(jwtData.OpenID)
+	person := utility.GetPerson(context)
 
 	// 判断用户权限
 	if person.Status == 0 {
@@ -42,9 +37,7 @@ func ChangeCaptain(context *gin.Context) {
 	}
 
 	// 判断队伍是否提交
-	teamID := strconv.Itoa(int(team.ID))
-	teamSubmitted, _ := global.Rdb.SIsMember(global.Rctx, "teams", teamID).Result()
-	if teamSubmitted {
+	if team.Submit {
 		utility.ResponseError(context, "该队伍已经提交，无法修改")
 		return
 	}
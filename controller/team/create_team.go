@@ -2,8 +2,11 @@ package team
 
 import (
 	"time"
+	"walk-server/constant"
 	"walk-server/global"
 	"walk-server/model"
+	"walk-server/service/teamService"
+	"walk-server/state"
 	"walk-server/utility"
 
 	"gorm.io/gorm"
@@ -21,10 +24,6 @@ type CreateTeamData struct {
 }
 
 func CreateTeam(context *gin.Context) {
-	// 获取 jwt 数据
-	jwtToken := context.GetHeader("Authorization")[7:]
-	jwtData, _ := utility.ParseToken(jwtToken)
-
 	// 获取 post json 数据
 	var createTeamData CreateTeamData
 	err := context.ShouldBindJSON(&createTeamData)
@@ -33,13 +32,13 @@ func CreateTeam(context *gin.Context) {
 		return
 	}
 
-	if createTeamData.Route > 6 {
+	if !constant.RouteExists(createTeamData.Route) {
 		utility.ResponseError(context, "参数错误")
 		return
 	}
 
 	// 查询用户信息
-	person, _ := model.GetPerson(jwtData.OpenID)
+	person := utility.GetPerson(context)
 
 	if person.Status != 0 { // 现在已经加入了一个团队
 		utility.ResponseError(context, "请先退出或解散原来的团队")
@@ -61,7 +60,7 @@ func CreateTeam(context *gin.Context) {
 		Slogan:     createTeamData.Slogan,
 		Point:      -1,
 		StartNum:   0,
-		Status:     1,
+		Status:     state.TeamNotStarted,
 		Time:       time.Now(),
 	}
 
@@ -81,14 +80,16 @@ func CreateTeam(context *gin.Context) {
 			return err
 		}
 
-		// 返回 nil 提交事务
-		return nil
+		return model.TxRecordMembership(tx, person.OpenId, &team, model.MembershipJoined, "创建队伍")
 	})
 	if err != nil {
 		utility.ResponseError(context, "服务异常，请重试")
 		return
 	}
 
+	// 已经有了队伍，撤回之前的入队申请
+	_ = teamService.WithdrawJoinRequests(person.OpenId)
+
 	// 返回 team_id
 	utility.ResponseSuccess(context, gin.H{
 		"team_id": team.ID,
@@ -1,21 +1,17 @@
 package team
 
 import (
-	"strconv"
 	"walk-server/global"
 	"walk-server/model"
+	"walk-server/service/teamService"
 	"walk-server/utility"
 
 	"github.com/gin-gonic/gin"
 )
 
 func GetTeamInfo(context *gin.Context) {
-	// 获取 jwt 数据
-	jwtToken := context.GetHeader("Authorization")[7:]
-	jwtData, _ := utility.ParseToken(jwtToken)
-
 	// 获取个人信息
-	person, _ := model.GetPerson(jwtData.OpenID)
+	person := utility.GetPerson(context)
 
 	// 先判断是否加入了团队
 	if person.Status == 0 {
@@ -61,8 +57,7 @@ func GetTeamInfo(context *gin.Context) {
 		})
 	}
 
-	teamID := strconv.Itoa(int(team.ID))
-	teamSubmitted, _ := global.Rdb.SIsMember(global.Rctx, "teams", teamID).Result()
+	waitlist, _ := teamService.GetWaitlistPosition(team.ID)
 
 	// 返回结果
 	utility.ResponseSuccess(context, gin.H{
@@ -70,7 +65,8 @@ func GetTeamInfo(context *gin.Context) {
 		"name":        team.Name,
 		"route":       team.Route,
 		"password":    team.Password,
-		"submitted":   teamSubmitted,
+		"submitted":   team.Submit,
+		"waitlist":    waitlist,
 		"allow_match": team.AllowMatch,
 		"slogan":      team.Slogan,
 		"point":       team.Point,
@@ -0,0 +1,139 @@
+package team
+
+import (
+	"errors"
+	"walk-server/model"
+	"walk-server/service/teamService"
+	"walk-server/utility"
+
+	"github.com/gin-gonic/gin"
+)
+
+type JoinRequestForm struct {
+	TeamID uint `json:"team_id" binding:"required"`
+}
+
+type HandleJoinRequestForm struct {
+	ID uint `json:"id" binding:"required"`
+}
+
+// joinRequestError 入队申请相关错误的提示
+func joinRequestError(context *gin.Context, err error) {
+	switch {
+	case errors.Is(err, teamService.ErrTeamNotFound):
+		utility.ResponseError(context, "找不到团队")
+	case errors.Is(err, teamService.ErrInTeam):
+		utility.ResponseError(context, "请退出或解散原来的团队")
+	case errors.Is(err, teamService.ErrNoJoinOp):
+		utility.ResponseError(context, "没有加入次数了")
+	case errors.Is(err, teamService.ErrTeamSubmitted):
+		utility.ResponseError(context, "该队伍已提交，无法加入")
+	case errors.Is(err, teamService.ErrTeamFull):
+		utility.ResponseError(context, "队伍人数到达上限")
+	case errors.Is(err, teamService.ErrTeacherJoinStudent):
+		utility.ResponseError(context, "您是教师，无法加入学生队伍")
+	case errors.Is(err, teamService.ErrJoinRequestExists):
+		utility.ResponseError(context, "已经申请过这支队伍，请等待队长处理")
+	case errors.Is(err, teamService.ErrTooManyJoinRequests):
+		utility.ResponseError(context, "待处理的申请过多，请等待队长处理或撤回申请")
+	case errors.Is(err, teamService.ErrJoinRequestNotFound):
+		utility.ResponseError(context, "申请不存在或已处理")
+	default:
+		utility.ResponseError(context, "服务异常，请重试")
+	}
+}
+
+// RequestJoin 申请加入队伍，不需要队伍密码，由队长同意后加入
+func RequestJoin(context *gin.Context) {
+	var postForm JoinRequestForm
+	if err := context.ShouldBindJSON(&postForm); err != nil {
+		utility.ResponseError(context, "参数错误")
+		return
+	}
+
+	person := utility.GetPerson(context)
+	request, err := teamService.RequestJoin(person, postForm.TeamID)
+	if err != nil {
+		joinRequestError(context, err)
+		return
+	}
+	utility.ResponseSuccess(context, gin.H{
+		"id": request.ID,
+	})
+}
+
+// GetJoinRequests 队长查看队伍待处理的申请
+func GetJoinRequests(context *gin.Context) {
+	person := utility.GetPerson(context)
+	if person.Status != 2 {
+		utility.ResponseError(context, "只有队长可以查看申请")
+		return
+	}
+
+	requests, err := teamService.ListJoinRequests(uint(person.TeamId))
+	if err != nil {
+		utility.ResponseError(context, "服务异常，请重试")
+		return
+	}
+	utility.ResponseSuccess(context, gin.H{
+		"requests": requests,
+	})
+}
+
+// GetMyJoinRequests 查看本人提交过的申请
+func GetMyJoinRequests(context *gin.Context) {
+	person := utility.GetPerson(context)
+	requests, err := teamService.ListMyJoinRequests(person.OpenId)
+	if err != nil {
+		utility.ResponseError(context, "服务异常，请重试")
+		return
+	}
+	utility.ResponseSuccess(context, gin.H{
+		"requests": requests,
+	})
+}
+
+// ApproveJoinRequest 队长同意申请
+func ApproveJoinRequest(context *gin.Context) {
+	handleJoinRequest(context, teamService.ApproveJoinRequest)
+}
+
+// RejectJoinRequest 队长拒绝申请
+func RejectJoinRequest(context *gin.Context) {
+	handleJoinRequest(context, teamService.RejectJoinRequest)
+}
+
+func handleJoinRequest(context *gin.Context, handle func(captain *model.Person, id uint) error) {
+	var postForm HandleJoinRequestForm
+	if err := context.ShouldBindJSON(&postForm); err != nil {
+		utility.ResponseError(context, "参数错误")
+		return
+	}
+
+	person := utility.GetPerson(context)
+	if person.Status != 2 {
+		utility.ResponseError(context, "只有队长可以处理申请")
+		return
+	}
+	if err := handle(person, postForm.ID); err != nil {
+		joinRequestError(context, err)
+		return
+	}
+	utility.ResponseSuccess(context, nil)
+}
+
+// CancelJoinRequest 撤回本人待处理的申请
+func CancelJoinRequest(context *gin.Context) {
+	var postForm HandleJoinRequestForm
+	if err := context.ShouldBindJSON(&postForm); err != nil {
+		utility.ResponseError(context, "参数错误")
+		return
+	}
+
+	person := utility.GetPerson(context)
+	if err := teamService.CancelJoinRequest(person, postForm.ID); err != nil {
+		joinRequestError(context, err)
+		return
+	}
+	utility.ResponseSuccess(context, nil)
+}
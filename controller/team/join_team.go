@@ -1,10 +1,11 @@
 package team
 
 import (
+	"errors"
 	"gorm.io/gorm"
-	"strconv"
 	"walk-server/global"
 	"walk-server/model"
+	"walk-server/service/teamService"
 	"walk-server/utility"
 
 	"github.com/gin-gonic/gin"
@@ -17,10 +18,6 @@ type JoinTeamData struct {
 }
 
 func JoinTeam(context *gin.Context) {
-	// 获取 jwt 数据
-	jwtToken := context.GetHeader("Authorization")[7:]
-	jwtData, _ := utility.ParseToken(jwtToken)
-
 	var joinTeamData JoinTeamData
 	err := context.ShouldBindJSON(&joinTeamData)
 	if err != nil { // 参数发送错误
@@ -29,7 +26,7 @@ func JoinTeam(context *gin.Context) {
 	}
 
 	// 从数据库中读取用户信息
-	person, _ := model.GetPerson(jwtData.OpenID)
+	person := utility.GetPerson(context)
 
 	if person.Status != 0 { // 如果在一个团队中
 		utility.ResponseError(context, "请退出或解散原来的团队")
@@ -52,9 +49,7 @@ func JoinTeam(context *gin.Context) {
 		return
 	}
 
-	teamID := strconv.Itoa(int(team.ID))
-	teamSubmitted, _ := global.Rdb.SIsMember(global.Rctx, "teams", teamID).Result()
-	if teamSubmitted {
+	if team.Submit {
 		utility.ResponseError(context, "该队伍已提交，无法加入")
 		return
 	}
@@ -74,24 +69,46 @@ func JoinTeam(context *gin.Context) {
 	}
 
 	err = global.DB.Transaction(func(tx *gorm.DB) error {
-		// 队伍成员数量加一
-		if err := tx.Model(&team).Update("num", team.Num+1).Error; err != nil {
+		// 队伍成员数量加一，在同一条语句中判断人数，避免并发加入超过上限
+		result := tx.Model(&model.Team{}).
+			Where("id = ? AND num < ? AND submit = ?", team.ID, 6, false).
+			Updates(map[string]any{"num": gorm.Expr("num + 1"), "version": gorm.Expr("version + 1")})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return teamService.ErrTeamFull
+		}
+		if err := tx.Where("id = ?", team.ID).Take(&team).Error; err != nil {
 			return err
 		}
 
-		// 更新加入成员的信息
+		// 更新加入成员的信息，按版本号更新，避免同时加入多支队伍
 		person.Status = 1
 		person.JoinOp--
 		person.TeamId = int(team.ID)
-		if err := model.TxUpdatePerson(tx, person); err != nil {
+		if err := model.TxUpdatePersonWithVersion(tx, person); err != nil {
 			return err
 		}
-
-		return nil
+		return model.TxRecordMembership(tx, person.OpenId, &team, model.MembershipJoined, "密码加入")
 	})
+	if errors.Is(err, teamService.ErrTeamFull) {
+		utility.ResponseError(context, "队伍人数到达上限")
+		return
+	} else if err != nil {
+		utility.ResponseError(context, "服务异常，请重试")
+		return
+	}
+	model.ClearPersonCache(person.OpenId)
 
 	// 加入成功以后发送消息给所有的用户
 	utility.SendMessageToTeam(person.Name+"加入了团队", captain, members)
 
+	// 撤回加入者的其他申请，满员后队伍的申请失效
+	_ = teamService.WithdrawJoinRequests(person.OpenId)
+	if team.Num >= 6 {
+		_ = teamService.ExpireJoinRequests(&team, "人数已满")
+	}
+
 	utility.ResponseSuccess(context, nil)
 }
@@ -2,7 +2,6 @@ package team
 
 import (
 	"gorm.io/gorm"
-	"strconv"
 	"walk-server/global"
 	"walk-server/model"
 	"walk-server/utility"
@@ -11,12 +10,8 @@ import (
 )
 
 func RemoveMember(context *gin.Context) {
-	// 获取 jwt 数据
-	jwtToken := context.GetHeader("Authorization")[7:]
-	jwtData, _ := utility.ParseToken(jwtToken)
-
 	// 查找用户
-	person, _ := model.GetPerson(jwtData.OpenID)
+	person := utility.GetPerson(context)
 
 	if person.Status == 0 {
 		utility.ResponseError(context, "请先加入团队")
@@ -28,9 +23,7 @@ func RemoveMember(context *gin.Context) {
 
 	var team model.Team
 	global.DB.Where("id = ?", person.TeamId).Take(&team)
-	teamID := strconv.Itoa(int(team.ID))
-	teamSubmitted, _ := global.Rdb.SIsMember(global.Rctx, "teams", teamID).Result()
-	if teamSubmitted {
+	if team.Submit {
 		utility.ResponseError(context, "该队伍已经提交, 无法移除队员")
 		return
 	}
@@ -49,7 +42,9 @@ func RemoveMember(context *gin.Context) {
 
 	err = global.DB.Transaction(func(tx *gorm.DB) error {
 		// 队伍成员数量减一
-		if err := tx.Model(&team).Update("num", team.Num-1).Error; err != nil {
+		err := tx.Model(&model.Team{}).Where("id = ?", team.ID).
+			Updates(map[string]any{"num": gorm.Expr("num - 1"), "version": gorm.Expr("version + 1")}).Error
+		if err != nil {
 			return err
 		}
 
@@ -60,7 +55,7 @@ func RemoveMember(context *gin.Context) {
 			return err
 		}
 
-		return nil
+		return model.TxRecordMembership(tx, personRemoved.OpenId, &team, model.MembershipLeft, "被队长移出")
 	})
 	if err != nil {
 		utility.ResponseError(context, "服务异常，请重试")
@@ -1,21 +1,18 @@
 package team
 
 import (
-	"strconv"
+	"errors"
 	"walk-server/global"
 	"walk-server/model"
+	"walk-server/service/teamService"
 	"walk-server/utility"
 
 	"github.com/gin-gonic/gin"
 )
 
 func RollBackTeam(context *gin.Context) {
-	// 获取 jwt 数据
-	jwtToken := context.GetHeader("Authorization")[7:]
-	jwtData, _ := utility.ParseToken(jwtToken)
-
 	// 查找用户
-	person, _ := model.GetPerson(jwtData.OpenID)
+	person := utility.GetPerson(context)
 
 	// 判断用户权限
 	if person.Status == 0 {
@@ -28,20 +25,13 @@ func RollBackTeam(context *gin.Context) {
 
 	var team model.Team
 	global.DB.Where("id = ?", person.TeamId).Take(&team)
-	teamID := strconv.Itoa(int(team.ID))
-	teamSubmitted, _ := global.Rdb.SIsMember(global.Rctx, "teams", teamID).Result()
-	if !teamSubmitted {
+	err := teamService.Rollback(&team)
+	if errors.Is(err, teamService.ErrTeamNotSubmitted) {
 		utility.ResponseError(context, "队伍未提交")
 		return
-	}
-
-	// 删除队伍的提交状态
-	global.Rdb.SRem(global.Rctx, "teams", teamID)
-	var dailyRouteKey string
-	if person.Type == 1 {
-		dailyRoute := utility.GetCurrentDate()*10 + team.Route
-		dailyRouteKey = strconv.Itoa(int(dailyRoute))
-		global.Rdb.Incr(global.Rctx, dailyRouteKey)
+	} else if err != nil {
+		utility.ResponseError(context, "系统异常，请重试")
+		return
 	}
 	utility.ResponseSuccess(context, nil)
 }
@@ -4,39 +4,15 @@ import (
 	"strconv"
 	"walk-server/global"
 	"walk-server/model"
+	"walk-server/service/teamService"
 	"walk-server/utility"
 
 	"github.com/gin-gonic/gin"
-	"github.com/redis/go-redis/v9"
 )
 
-// 编写Lua脚本 - 判断是否已经提交、判断是否达到上限、提交后将剩余数量减一并记录提交的团队id
-var submit = redis.NewScript(`
-local teamID = KEYS[1];
-local dailyRouteKey = KEYS[2];
-
-local teamExists = redis.call("sismember", "teams", teamID);
-if tonumber(teamExists) == 1 then
-	return 1;
-end
-
-local num=redis.call("get", dailyRouteKey);
-if tonumber(num) <= 0 then
-	return 2;
-end
-
-redis.call("decr", dailyRouteKey);
-redis.call("SAdd", "teams", teamID);
-return 0;
-`)
-
 func SubmitTeam(context *gin.Context) {
-	// 获取 jwt 数据
-	jwtToken := context.GetHeader("Authorization")[7:]
-	jwtData, _ := utility.ParseToken(jwtToken)
-
 	// 查找用户
-	person, _ := model.GetPerson(jwtData.OpenID)
+	person := utility.GetPerson(context)
 
 	// 判断用户权限
 	if person.Status == 0 {
@@ -58,21 +34,19 @@ func SubmitTeam(context *gin.Context) {
 		return
 	}
 
-	teamID := strconv.Itoa(int(team.ID))
-	dailyRoute := utility.GetCurrentDate()*10 + team.Route
-	dailyRouteKey := strconv.Itoa(int(dailyRoute))
-	// 运行Lua脚本
-	n, err := submit.Run(global.Rctx, global.Rdb, []string{teamID, dailyRouteKey}).Result()
+	// 名额已满时加入候补，有名额空出时按顺序自动提交
+	n, pos, err := teamService.Submit(&team)
 	if err != nil {
 		utility.ResponseError(context, "系统异常，请重试")
 		return
 	}
 
-	if n.(int64) == 1 {
+	switch n {
+	case teamService.SubmitSubmitted:
 		utility.ResponseError(context, "队伍已提交")
 		return
-	} else if n.(int64) == 2 {
-		utility.ResponseError(context, "队伍数量已经到达上限，无法提交")
+	case teamService.SubmitWaitlist, teamService.SubmitWaiting:
+		utility.ResponseError(context, "队伍数量已经到达上限，已加入候补，当前排在第 "+strconv.Itoa(int(pos))+" 位")
 		return
 	}
 	utility.ResponseSuccess(context, nil)
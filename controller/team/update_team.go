@@ -1,7 +1,7 @@
 package team
 
 import (
-	"strconv"
+	"walk-server/constant"
 	"walk-server/global"
 	"walk-server/model"
 	"walk-server/utility"
@@ -19,12 +19,8 @@ type UpdateTeamData struct {
 }
 
 func UpdateTeam(context *gin.Context) {
-	// 获取 jwt 数据
-	jwtToken := context.GetHeader("Authorization")[7:]
-	jwtData, _ := utility.ParseToken(jwtToken)
-
 	// 查找用户
-	person, _ := model.GetPerson(jwtData.OpenID)
+	person := utility.GetPerson(context)
 
 	// 判断用户权限
 	if person.Status == 0 {
@@ -42,13 +38,15 @@ func UpdateTeam(context *gin.Context) {
 		utility.ResponseError(context, "参数错误")
 		return
 	}
+	if !constant.RouteExists(updateTeamData.Route) {
+		utility.ResponseError(context, "参数错误")
+		return
+	}
 
 	// 更新团队信息
 	var team model.Team
 	global.DB.Where("id = ?", person.TeamId).Take(&team)
-	teamID := strconv.Itoa(int(team.ID))
-	teamSubmitted, _ := global.Rdb.SIsMember(global.Rctx, "teams", teamID).Result()
-	if teamSubmitted {
+	if team.Submit {
 		utility.ResponseError(context, "该队伍已经提交，无法修改")
 		return
 	}
@@ -0,0 +1,120 @@
+package user
+
+import (
+	"walk-server/global"
+	"walk-server/model"
+	"walk-server/utility"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExportData 导出服务器保存的与用户本人有关的全部数据
+func ExportData(context *gin.Context) {
+	// 获取 open ID
+	jwtData := utility.GetJwtData(context) // 中间件校验过数据了
+	openID := jwtData.OpenID
+
+	// 获取用户数据
+	person, _ := model.GetPerson(openID)
+
+	data := gin.H{
+		"person": gin.H{
+			"name":        person.Name,
+			"gender":      person.Gender,
+			"stu_id":      person.StuId,
+			"identity":    person.Identity,
+			"campus":      person.Campus,
+			"college":     person.College,
+			"type":        person.Type,
+			"status":      person.Status,
+			"create_op":   person.CreatedOp,
+			"join_op":     person.JoinOp,
+			"team_id":     person.TeamId,
+			"walk_status": person.WalkStatus,
+			"contact": gin.H{
+				"qq":     person.Qq,
+				"wechat": person.Wechat,
+				"tel":    person.Tel,
+			},
+		},
+	}
+
+	// 所在队伍和队伍的扫码记录
+	if person.TeamId > 0 {
+		team, err := model.GetTeamInfo(uint(person.TeamId))
+		if err == nil {
+			captain, members := model.GetPersonsInTeam(person.TeamId)
+			names := []string{captain.Name}
+			for _, member := range members {
+				names = append(names, member.Name)
+			}
+			passages, err := model.GetPassages(team.ID)
+			if err != nil {
+				utility.ResponseError(context, "服务异常，请重试")
+				return
+			}
+			data["team"] = gin.H{
+				"id":       team.ID,
+				"name":     team.Name,
+				"route":    team.Route,
+				"slogan":   team.Slogan,
+				"status":   team.Status,
+				"point":    team.Point,
+				"submit":   team.Submit,
+				"members":  names,
+				"passages": passages,
+			}
+		}
+	}
+
+	// 加入和离开队伍的记录
+	memberships, err := model.GetMemberships(openID)
+	if err != nil {
+		utility.ResponseError(context, "服务异常，请重试")
+		return
+	}
+	data["team_history"] = memberships
+
+	// 收到和发出的消息
+	var messages []model.Message
+	result := global.DB.Where("receiver_open_id = ? OR sender_open_id = ?", openID, openID).Order("id").Find(&messages)
+	if result.Error != nil {
+		utility.ResponseError(context, "服务异常，请重试")
+		return
+	}
+	messageData := make([]gin.H, 0, len(messages))
+	for _, message := range messages {
+		messageData = append(messageData, gin.H{
+			"message":    message.Message,
+			"system":     message.SenderOpenId == "",
+			"received":   message.ReceiverOpenId == openID,
+			"created_at": message.CreatedAt,
+		})
+	}
+	data["messages"] = messageData
+
+	// 工作人员对本人的操作记录
+	var forms []model.Form
+	result = global.DB.Select("action", "route", "point", "code", "message", "time").
+		Where("id IN (?)", global.DB.Model(&model.FormTarget{}).Select("form_id").Where("person_id = ?", openID)).
+		Order("time").
+		Find(&forms)
+	if result.Error != nil {
+		utility.ResponseError(context, "服务异常，请重试")
+		return
+	}
+	operations := make([]gin.H, 0, len(forms))
+	for _, form := range forms {
+		operations = append(operations, gin.H{
+			"action":  form.Action,
+			"route":   form.Route,
+			"point":   form.Point,
+			"code":    form.Code,
+			"message": form.Message,
+			"time":    form.Time,
+		})
+	}
+	data["operations"] = operations
+
+	utility.ResponseSuccess(context, data)
+}
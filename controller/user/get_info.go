@@ -9,8 +9,7 @@ import (
 
 func GetInfo(context *gin.Context) {
 	// 获取 open ID
-	jwtToken := context.GetHeader("Authorization")[7:]
-	jwtData, _ := utility.ParseToken(jwtToken) // 中间件校验过数据了
+	jwtData := utility.GetJwtData(context) // 中间件校验过数据了
 	openID := jwtData.OpenID
 
 	// 获取用户数据
@@ -18,8 +18,7 @@ type UserModifyData struct {
 
 func ModifyInfo(context *gin.Context) {
 	// 获取 open ID
-	jwtToken := context.GetHeader("Authorization")[7:]
-	jwtData, _ := utility.ParseToken(jwtToken) // 中间件校验过数据了
+	jwtData := utility.GetJwtData(context) // 中间件校验过数据了
 	openID := jwtData.OpenID
 
 	// 获取 post data
@@ -38,6 +37,9 @@ func ModifyInfo(context *gin.Context) {
 	person.Tel = postData.Contact.Tel
 
 	// 更新数据
-	model.UpdatePerson(openID, person)
+	if err := model.UpdatePerson(openID, person); err != nil {
+		utility.ResponseError(context, "服务异常，请重试")
+		return
+	}
 	utility.ResponseSuccess(context, nil)
 }
@@ -0,0 +1,59 @@
+package user
+
+import (
+	"walk-server/service/sessionService"
+	"walk-server/utility"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetSessions 获取本人全部有效的登录会话
+func GetSessions(context *gin.Context) {
+	jwtData := utility.GetJwtData(context) // 中间件校验过数据了
+
+	sessions, err := sessionService.List(utility.KindUser, jwtData.OpenID)
+	if err != nil {
+		utility.ResponseError(context, "服务异常，请重试")
+		return
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == jwtData.Session
+	}
+	utility.ResponseSuccess(context, gin.H{
+		"sessions": sessions,
+	})
+}
+
+type RevokeSessionForm struct {
+	SessionID string `json:"session_id"` // 为空时退出当前会话
+	All       bool   `json:"all"`        // 退出除当前会话以外的全部会话
+}
+
+// RevokeSession 退出登录，或者让其他设备上的登录失效
+func RevokeSession(context *gin.Context) {
+	var postForm RevokeSessionForm
+	if err := context.ShouldBindJSON(&postForm); err != nil {
+		utility.ResponseError(context, "参数错误")
+		return
+	}
+	jwtData := utility.GetJwtData(context)
+
+	var err error
+	switch {
+	case postForm.All:
+		err = sessionService.RevokeAll(utility.KindUser, jwtData.OpenID, jwtData.Session)
+	case postForm.SessionID != "":
+		if !sessionService.Owns(utility.KindUser, jwtData.OpenID, postForm.SessionID) {
+			utility.ResponseError(context, "会话不存在")
+			return
+		}
+		err = sessionService.Revoke(postForm.SessionID)
+	default:
+		err = sessionService.Revoke(jwtData.Session)
+	}
+	if err != nil {
+		utility.ResponseError(context, "服务异常，请重试")
+		return
+	}
+	utility.ResponseSuccess(context, nil)
+}
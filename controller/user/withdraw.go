@@ -0,0 +1,57 @@
+package user
+
+import (
+	"errors"
+	"walk-server/service/sessionService"
+	"walk-server/service/teamService"
+	"walk-server/service/userService"
+	"walk-server/state"
+	"walk-server/utility"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Withdraw 用户退出活动并注销，先离开或解散队伍，再清除个人信息
+func Withdraw(context *gin.Context) {
+	// 获取 open ID
+	jwtData := utility.GetJwtData(context) // 中间件校验过数据了
+
+	// 获取用户数据
+	person := utility.GetPerson(context)
+	if person.WalkStatus != state.WalkNotStarted {
+		utility.ResponseError(context, "活动已开始，无法注销")
+		return
+	}
+	if person.Status != 0 && utility.IsExpired() {
+		utility.ResponseError(context, "报名已截止，请联系工作人员退出队伍")
+		return
+	}
+
+	var err error
+	switch person.Status {
+	case 1:
+		err = teamService.Leave(person)
+	case 2:
+		err = teamService.Disband(person)
+	}
+	if errors.Is(err, teamService.ErrTeamSubmitted) {
+		utility.ResponseError(context, "队伍已提交，请先撤销提交")
+		return
+	} else if err != nil {
+		utility.ResponseError(context, "服务异常，请重试")
+		return
+	}
+
+	// 撤回待处理的入队申请，避免注销后队长仍能同意
+	if err := teamService.WithdrawJoinRequests(person.OpenId); err != nil {
+		utility.ResponseError(context, "服务异常，请重试")
+		return
+	}
+	if err := userService.Anonymize(person.OpenId); err != nil {
+		utility.ResponseError(context, "服务异常，请重试")
+		return
+	}
+	// 注销后全部登录会话失效
+	_ = sessionService.RevokeAll(utility.KindUser, jwtData.OpenID, "")
+	utility.ResponseSuccess(context, nil)
+}
@@ -13,9 +13,16 @@ import (
 func main() {
 	initial.ConfigInit() // 读取配置
 	initial.DBInit()     // 初始化数据库
+	initial.AdminInit()  // 迁移管理员密码并初始化超级管理员
 	initial.RedisInit()  // 初始化Redis
+	initial.QuotaInit()  // 初始化每天各路线报名上限
 	initial.LimitInit()  // 初始化令牌桶
 	initial.ConstantInit()
+	initial.PrivacyInit()   // 初始化个人信息加密
+	initial.DashboardInit() // 初始化大屏看板计数
+	initial.IdentityInit()  // 初始化报名身份验证方式
+	initial.FileInit()      // 定时清理生成的文件
+	initial.ReconcileInit() // 定时比对并修复提交状态
 	wechat.WeChatInit()
 
 	// 如果配置文件中开启了调试模式
@@ -25,7 +32,6 @@ func main() {
 
 	// 初始化路由
 	r := initial.RouterInit()
-	r.Static("/file", "./file")
 	//r.Use(middleware.Time())
 	router.MountRoutes(r)
 
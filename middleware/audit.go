@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"encoding/json"
+	"strings"
+	"walk-server/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 审计日志中需要隐藏的字段
+var sensitiveKeys = []string{"password", "token"}
+
+// redact 隐藏请求数据中的密码和凭证
+func redact(data map[string]interface{}) map[string]interface{} {
+	for key := range data {
+		lower := strings.ToLower(key)
+		for _, sensitive := range sensitiveKeys {
+			if strings.Contains(lower, sensitive) {
+				data[key] = "***"
+				break
+			}
+		}
+	}
+	return data
+}
+
+func getAudit(context *gin.Context) *model.Form {
+	value, _ := context.Get("audit")
+	form, _ := value.(*model.Form)
+	return form
+}
+
+// AuditTarget 记录本次操作涉及的队伍和队员
+func AuditTarget(context *gin.Context, teamID uint, personIDs ...string) {
+	form := getAudit(context)
+	if form == nil {
+		return
+	}
+	if len(personIDs) == 0 {
+		form.Targets = append(form.Targets, model.FormTarget{TeamID: teamID})
+		return
+	}
+	for _, personID := range personIDs {
+		form.Targets = append(form.Targets, model.FormTarget{TeamID: teamID, PersonID: personID})
+	}
+}
+
+// AuditChange 记录本次操作前后的状态
+func AuditChange(context *gin.Context, before any, after any) {
+	form := getAudit(context)
+	if form == nil {
+		return
+	}
+	form.Before, _ = json.Marshal(before)
+	form.After, _ = json.Marshal(after)
+}
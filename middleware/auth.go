@@ -2,33 +2,50 @@ package middleware
 
 import (
 	"walk-server/model"
+	"walk-server/service/sessionService"
 	"walk-server/utility"
 
 	"github.com/gin-gonic/gin"
 )
 
-func IsRegistered(context *gin.Context) {
-	jwtToken := context.GetHeader("Authorization")
-	if jwtToken == "" {
+// authenticateUser 解析参与者的 bearer token 并检查会话是否已撤销，成功后写入上下文
+func authenticateUser(context *gin.Context) (*utility.JwtData, bool) {
+	jwtToken, ok := utility.BearerToken(context)
+	if !ok {
 		utility.ResponseError(context, "缺少登录凭证")
 		context.Abort()
-		return
-	} else {
-		jwtToken = jwtToken[7:]
+		return nil, false
 	}
-	jwtData, err := utility.ParseToken(jwtToken)
+	jwtData, err := utility.ParseUserToken(jwtToken)
 	// jwt token 解析失败
 	if err != nil {
 		utility.ResponseError(context, "jwt error")
 		context.Abort()
+		return nil, false
+	}
+	if jwtData.Session == "" || sessionService.IsRevoked(jwtData.Session) {
+		utility.ResponseError(context, "登录已失效，请重新登录")
+		context.Abort()
+		return nil, false
+	}
+	utility.SetJwtData(context, jwtData)
+	return jwtData, true
+}
+
+// IsRegistered 检查参与者已经登录并报名，当前用户通过 utility.GetPerson 获取
+func IsRegistered(context *gin.Context) {
+	jwtData, ok := authenticateUser(context)
+	if !ok {
 		return
 	}
 
-	if _, err = model.GetPerson(jwtData.OpenID); err != nil {
+	person, err := model.GetPerson(jwtData.OpenID)
+	if err != nil {
 		utility.ResponseError(context, "请先报名注册")
 		context.Abort()
 		return
 	}
+	utility.SetPerson(context, person)
 
 	context.Next()
 }
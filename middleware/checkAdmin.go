@@ -5,57 +5,67 @@ import (
 	"encoding/json"
 	"github.com/gin-gonic/gin"
 	"io"
-	"strconv"
+	"log"
+	"strings"
 	"time"
-	"walk-server/global"
 	"walk-server/model"
 	"walk-server/service/adminService"
+	"walk-server/service/sessionService"
 	"walk-server/utility"
 )
 
 func CheckAdmin(context *gin.Context) {
-	jwtToken := context.GetHeader("Authorization")
-	if jwtToken == "" {
+	checkAdmin(context, false)
+}
+
+// CheckAdminAllowMustChange 与 CheckAdmin 相同，但允许需要修改密码的管理员访问，仅用于修改密码接口
+func CheckAdminAllowMustChange(context *gin.Context) {
+	checkAdmin(context, true)
+}
+
+func checkAdmin(context *gin.Context, allowMustChange bool) {
+	jwtToken, ok := utility.BearerToken(context)
+	if !ok {
 		utility.ResponseError(context, "缺少登录凭证")
 		context.Abort()
 		return
-	} else {
-		jwtToken = jwtToken[7:]
-	}
-	jwtData, err := utility.ParseToken(jwtToken)
-	// jwt token 解析失败
-	if err != nil {
-		utility.ResponseError(context, "jwt error")
-		context.Abort()
-		return
 	}
-
-	userID := utility.AesDecrypt(jwtData.OpenID, global.Config.GetString("server.AESSecret"))
-	user_id, err := strconv.Atoi(userID)
+	jwtData, err := utility.ParseAdminToken(jwtToken)
+	// jwt token 解析失败，参与者的 token 也会在这里被拒绝
 	if err != nil {
 		utility.ResponseError(context, "jwt error")
 		context.Abort()
 		return
 	}
-
-	user, err := adminService.GetAdminByID(uint(user_id))
-	if err != nil {
-		utility.ResponseError(context, "jwt error")
-		context.Abort()
-		return
-	}
-
-	if user == nil {
-		utility.ResponseError(context, "未登陆")
+	user, ok := authenticateAdmin(context, jwtData, allowMustChange)
+	if !ok {
 		return
 	}
 
 	var requestData map[string]interface{}
 	var jsonData []byte
 
-	// 尝试从请求体中获取数据
-	rawData, err := context.GetRawData()
-	if err == nil && len(rawData) > 0 {
+	// 上传文件的请求只记录表单字段和文件名
+	if context.ContentType() == gin.MIMEMultipartPOSTForm {
+		multipartForm, err := context.MultipartForm()
+		if err != nil {
+			utility.ResponseError(context, "请求体解析失败")
+			context.Abort()
+			return
+		}
+		requestData = make(map[string]interface{})
+		for key, values := range multipartForm.Value {
+			if len(values) > 0 {
+				requestData[key] = values[0]
+			}
+		}
+		for key, files := range multipartForm.File {
+			if len(files) > 0 {
+				requestData[key] = files[0].Filename
+			}
+		}
+		jsonData, _ = json.Marshal(redact(requestData))
+	} else if rawData, err := context.GetRawData(); err == nil && len(rawData) > 0 {
 		// 请求体有数据，解析为 JSON
 		if err := json.Unmarshal(rawData, &requestData); err != nil {
 			utility.ResponseError(context, "请求体解析失败")
@@ -64,7 +74,7 @@ func CheckAdmin(context *gin.Context) {
 		}
 
 		// 将 requestData 转换为 JSON
-		jsonData, err = json.Marshal(requestData)
+		jsonData, err = json.Marshal(redact(requestData))
 		if err != nil {
 			utility.ResponseError(context, "数据序列化失败")
 			context.Abort()
@@ -85,7 +95,7 @@ func CheckAdmin(context *gin.Context) {
 			}
 
 			// 将 query 参数转换为 JSON
-			jsonData, err = json.Marshal(requestData)
+			jsonData, err = json.Marshal(redact(requestData))
 			if err != nil {
 				utility.ResponseError(context, "query 数据序列化失败")
 				context.Abort()
@@ -96,17 +106,86 @@ func CheckAdmin(context *gin.Context) {
 			jsonData = []byte("{}")
 		}
 	}
-	err = model.InsertForm(model.Form{
+
+	// 请求处理完成后记录审计日志，处理过程中可以通过 AuditTarget 和 AuditChange 补充操作对象和状态变化
+	form := &model.Form{
 		AdminID: user.ID,
 		Route:   user.Route,
 		Point:   user.Point,
+		Action:  strings.TrimPrefix(context.FullPath(), "/api/v1/admin/"),
+		Method:  context.Request.Method,
 		Data:    jsonData,
 		Time:    time.Now(),
-	})
+	}
+	context.Set("audit", form)
+	context.Next()
+
+	form.Code = context.GetInt("response_code")
+	form.Message = context.GetString("response_msg")
+	if err := model.InsertForm(*form); err != nil {
+		log.Println("审计日志写入失败: ", err)
+	}
+}
+
+// authenticateAdmin 检查会话是否已撤销、签发后权限是否变化，成功后把管理员写入上下文
+func authenticateAdmin(context *gin.Context, jwtData *utility.JwtData, allowMustChange bool) (*model.Admin, bool) {
+	if jwtData.Session == "" || sessionService.IsRevoked(jwtData.Session) {
+		utility.ResponseError(context, "登录已失效，请重新登录")
+		context.Abort()
+		return nil, false
+	}
+
+	user, err := adminService.GetAdminByID(jwtData.AdminID)
+	if err != nil {
+		utility.ResponseError(context, "jwt error")
+		context.Abort()
+		return nil, false
+	}
+
+	if user == nil {
+		utility.ResponseError(context, "未登陆")
+		context.Abort()
+		return nil, false
+	}
+	// 签发后角色、路线或点位被修改过的 token 需要重新登录
+	if jwtData.Role != user.Role || jwtData.Route != user.Route || jwtData.Point != user.Point {
+		utility.ResponseError(context, "权限已变更，请重新登录")
+		context.Abort()
+		return nil, false
+	}
+	if user.MustChange && !allowMustChange {
+		utility.ResponseError(context, "请先修改密码")
+		context.Abort()
+		return nil, false
+	}
+	utility.SetJwtData(context, jwtData)
+	utility.SetAdmin(context, user)
+	return user, true
+}
+
+// CheckStreamToken 校验大屏推送的短期 token，浏览器的 EventSource 不能设置请求头，token 放在查询参数 token 中
+func CheckStreamToken(context *gin.Context) {
+	jwtData, err := utility.ParseStreamToken(context.Query("token"))
 	if err != nil {
 		utility.ResponseError(context, "jwt error")
 		context.Abort()
 		return
 	}
+	if _, ok := authenticateAdmin(context, jwtData, false); !ok {
+		return
+	}
 	context.Next()
 }
+
+// RequireRole 检查管理员角色，需要放在 CheckAdmin 之后
+func RequireRole(roles ...uint8) gin.HandlerFunc {
+	return func(context *gin.Context) {
+		user := utility.GetAdmin(context)
+		if user == nil || !user.HasRole(roles...) {
+			utility.ResponseError(context, "没有权限")
+			context.Abort()
+			return
+		}
+		context.Next()
+	}
+}
@@ -1,15 +1,19 @@
 package middleware
 
-import "walk-server/model"
+import (
+	"walk-server/model"
+	"walk-server/service/routeService"
+)
 
-// CheckRoute 检查管理员权限
+// CheckRoute 检查管理员权限，有共用地点的路线之间可以互相扫码
 func CheckRoute(admin *model.Admin, team *model.Team) bool {
-	if team.Route == admin.Route {
-		return true
-	} else if (team.Route == 4 && admin.Route == 5) || (team.Route == 5 && admin.Route == 4) {
-		return true
-	} else if (team.Route == 2 && admin.Route == 3) || (team.Route == 3 && admin.Route == 2) {
+	return CheckRouteID(admin, team.Route)
+}
+
+// CheckRouteID 检查管理员能否管理该路线，超级管理员可以管理全部路线
+func CheckRouteID(admin *model.Admin, route uint8) bool {
+	if admin.Role == model.RoleSuper {
 		return true
 	}
-	return false
+	return routeService.IsConnected(route, admin.Route)
 }
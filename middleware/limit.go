@@ -22,9 +22,13 @@ func TokenRateLimiter(context *gin.Context) {
 
 // 限制单个用户每秒请求次数
 func PerRateLimiter(context *gin.Context) {
-	// 获取 jwt 数据
-	jwtToken := context.GetHeader("Authorization")[7:]
-	jwtData, _ := utility.ParseToken(jwtToken)
+	// 获取 jwt 数据，需要放在鉴权中间件之后
+	jwtData := utility.GetJwtData(context)
+	if jwtData == nil {
+		utility.ResponseError(context, "缺少登录凭证")
+		context.Abort()
+		return
+	}
 
 	// 每秒刷新
 	global.Rdb.SetNX(global.Rctx, jwtData.OpenID+"Limit", 0, time.Minute)
@@ -1,8 +1,6 @@
 package middleware
 
 import (
-	"time"
-	"walk-server/global"
 	"walk-server/utility"
 
 	"github.com/gin-gonic/gin"
@@ -21,15 +19,7 @@ func TimeValidity(ctx *gin.Context) {
 
 // IsExpired 检查是否过了报名时间，报名时间过了就无法修改用户信息了
 func IsExpired(context *gin.Context) {
-	expiredTime, _ := time.ParseInLocation(
-		time.DateTime,
-		global.Config.GetString("expiredDate"),
-		time.Local,
-	)
-	expiredTimeUnix := expiredTime.Unix()
-	deltaTimeUnix := time.Now().Unix() - expiredTimeUnix
-
-	if deltaTimeUnix >= 0 { // 过期了
+	if utility.IsExpired() { // 过期了
 		utility.ResponseError(context, "报名截止了哦")
 		context.Abort()
 	} else {
@@ -47,22 +37,9 @@ func CanSubmit(context *gin.Context) {
 	}
 }
 
-// RegisterJWTValidity 注册的时候验证 JWT 是否合法
+// RegisterJWTValidity 注册的时候验证 JWT 是否合法，jwt 数据通过 utility.GetJwtData 获取
 func RegisterJWTValidity(context *gin.Context) {
-	jwtToken := context.GetHeader("Authorization")
-	if jwtToken == "" {
-		utility.ResponseError(context, "缺少登录凭证")
-		context.Abort()
-		return
-	} else {
-		jwtToken = jwtToken[7:]
-	}
-	_, err := utility.ParseToken(jwtToken)
-
-	if err != nil {
-		utility.ResponseError(context, "请先登录")
-		context.Abort()
-	} else {
+	if _, ok := authenticateUser(context); ok {
 		context.Next() // 转到 controller 继续执行
 	}
 }
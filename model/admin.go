@@ -1,5 +1,15 @@
 package model
 
+import "slices"
+
+// 管理员角色
+const (
+	RoleSuper     uint8 = 1 // 超级管理员
+	RoleRouteLead uint8 = 2 // 路线负责人
+	RoleVolunteer uint8 = 3 // 点位志愿者
+	RoleObserver  uint8 = 4 // 只读观察员
+)
+
 type Admin struct {
 	ID           uint   `json:"admin_id"`
 	WechatOpenID string `json:"-"`
@@ -8,4 +18,11 @@ type Admin struct {
 	Password     string `json:"-"`
 	Point        int8   `json:"point"`
 	Route        uint8  `json:"route"` // 1 是朝晖路线，2 屏峰半程，3 屏峰全程，4 莫干山半程，5 莫干山全程
+	Role         uint8  `json:"role" gorm:"not null;default:3;comment:角色(1超级管理员,2路线负责人,3点位志愿者,4观察员)"`
+	MustChange   bool   `json:"must_change" gorm:"not null;default:false;comment:下次登录需要修改密码"`
+}
+
+// HasRole 判断管理员是否属于给定角色之一
+func (a *Admin) HasRole(roles ...uint8) bool {
+	return slices.Contains(roles, a.Role)
 }
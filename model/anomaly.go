@@ -0,0 +1,24 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ScanAnomaly 扫码顺序异常记录，供管理员事后复核
+type ScanAnomaly struct {
+	ID        uint      `json:"id"`
+	TeamID    uint      `json:"team_id" gorm:"not null;index;comment:队伍ID"`
+	Route     uint8     `json:"route" gorm:"not null;index;comment:路线"`
+	Type      uint8     `json:"type" gorm:"not null;comment:类型(1跳过点位,2强制回退)"`
+	FromPoint int8      `json:"from_point" gorm:"not null;comment:扫码前点位"`
+	ToPoint   int8      `json:"to_point" gorm:"not null;comment:扫码后点位"`
+	AdminID   uint      `json:"admin_id" gorm:"not null;comment:扫码管理员ID"`
+	Reviewed  bool      `json:"reviewed" gorm:"not null;default:false;comment:是否已复核"`
+	Time      time.Time `json:"time" gorm:"not null;comment:扫码时间"`
+}
+
+func TxInsertAnomaly(tx *gorm.DB, anomaly ScanAnomaly) error {
+	return tx.Create(&anomaly).Error
+}
@@ -5,13 +5,29 @@ import (
 	"walk-server/global"
 )
 
+// Form 管理端审计日志，每个管理端请求记录一条
 type Form struct {
-	ID      uint      `json:"id"`       //主键
-	AdminID uint      `json:"admin_id"` //管理员ID
-	Route   uint8     `json:"route"`    //路线  1 是朝晖路线，2 屏峰半程，3 屏峰全程，4 莫干山半程，5 莫干山全程
-	Point   int8      `json:"point"`    //点位
-	Data    []byte    `json:"data"`     //数据
-	Time    time.Time `json:"time"`     //时间
+	ID      uint         `json:"id"`                                           //主键
+	AdminID uint         `json:"admin_id" gorm:"index"`                        //管理员ID
+	Route   uint8        `json:"route"`                                        //路线  1 是朝晖路线，2 屏峰半程，3 屏峰全程，4 莫干山半程，5 莫干山全程
+	Point   int8         `json:"point"`                                        //点位
+	Action  string       `json:"action" gorm:"size:64;index;comment:操作(接口路径)"` //操作
+	Method  string       `json:"method" gorm:"size:8"`                         //请求方法
+	Data    []byte       `json:"data"`                                         //数据
+	Before  []byte       `json:"before" gorm:"comment:操作前状态"`                  //操作前状态
+	After   []byte       `json:"after" gorm:"comment:操作后状态"`                   //操作后状态
+	Code    int          `json:"code" gorm:"comment:响应状态(200成功,-1失败)"`         //响应状态
+	Message string       `json:"message" gorm:"size:255"`                      //响应信息
+	Time    time.Time    `json:"time" gorm:"index"`                            //时间
+	Targets []FormTarget `json:"targets" gorm:"foreignKey:FormID"`             //操作涉及的队伍和队员
+}
+
+// FormTarget 审计日志涉及的队伍和队员，用于按队伍或队员查询
+type FormTarget struct {
+	ID       uint   `json:"-"`
+	FormID   uint   `json:"-" gorm:"index"`
+	TeamID   uint   `json:"team_id" gorm:"index"`
+	PersonID string `json:"person_id" gorm:"size:64;index"` // 队员 OpenID
 }
 
 func InsertForm(form Form) error {
@@ -0,0 +1,38 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 入队申请状态
+const (
+	JoinRequestPending   uint8 = 0 // 等待队长处理
+	JoinRequestApproved  uint8 = 1 // 已同意
+	JoinRequestRejected  uint8 = 2 // 已拒绝
+	JoinRequestExpired   uint8 = 3 // 队伍提交、满员或解散后失效
+	JoinRequestCancelled uint8 = 4 // 申请人撤回或已加入其他队伍
+)
+
+// JoinRequest 参与者申请加入队伍，由队长同意或拒绝，作为分享队伍密码之外的入队方式
+type JoinRequest struct {
+	ID        uint      `json:"id"`
+	TeamID    uint      `json:"team_id" gorm:"not null;index;comment:队伍ID"`
+	OpenId    string    `json:"-" gorm:"size:64;not null;index;comment:申请人OpenID"`
+	Status    uint8     `json:"status" gorm:"not null;default:0;comment:状态(0待处理,1同意,2拒绝,3失效,4撤回)"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TxExpireJoinRequests 在事务中把队伍全部待处理的申请标记为失效，返回被标记的申请
+func TxExpireJoinRequests(tx *gorm.DB, teamID uint) ([]JoinRequest, error) {
+	var requests []JoinRequest
+	err := tx.Where("team_id = ? AND status = ?", teamID, JoinRequestPending).Find(&requests).Error
+	if err != nil || len(requests) == 0 {
+		return nil, err
+	}
+	err = tx.Model(&JoinRequest{}).Where("team_id = ? AND status = ?", teamID, JoinRequestPending).
+		Update("status", JoinRequestExpired).Error
+	return requests, err
+}
@@ -0,0 +1,44 @@
+package model
+
+import (
+	"time"
+	"walk-server/global"
+
+	"gorm.io/gorm"
+)
+
+// 队伍成员变动
+const (
+	MembershipJoined uint8 = 1 // 加入队伍
+	MembershipLeft   uint8 = 2 // 离开队伍
+)
+
+// Membership 参与者加入和离开队伍的记录，用于导出本人的队伍历史
+type Membership struct {
+	ID       uint      `json:"-"`
+	OpenId   string    `json:"-" gorm:"size:64;not null;index;comment:参与者OpenID"`
+	TeamID   uint      `json:"team_id" gorm:"not null;comment:队伍ID"`
+	TeamName string    `json:"team_name" gorm:"size:64;comment:变动时的队伍名称"`
+	Action   uint8     `json:"action" gorm:"not null;comment:变动(1加入,2离开)"`
+	Reason   string    `json:"reason" gorm:"size:32;comment:变动原因"`
+	Time     time.Time `json:"time" gorm:"not null"`
+}
+
+// TxRecordMembership 在事务中记录参与者加入或离开队伍
+func TxRecordMembership(tx *gorm.DB, openID string, team *Team, action uint8, reason string) error {
+	return tx.Create(&Membership{
+		OpenId:   openID,
+		TeamID:   team.ID,
+		TeamName: team.Name,
+		Action:   action,
+		Reason:   reason,
+		Time:     time.Now(),
+	}).Error
+}
+
+// GetMemberships 按时间顺序获取参与者的队伍历史
+func GetMemberships(openID string) ([]Membership, error) {
+	var memberships []Membership
+	err := global.DB.Where("open_id = ?", openID).Order("time, id").Find(&memberships).Error
+	return memberships, err
+}
@@ -0,0 +1,30 @@
+package model
+
+import (
+	"time"
+	"walk-server/global"
+
+	"gorm.io/gorm"
+)
+
+// CheckpointPassage 队伍经过点位的扫码记录
+type CheckpointPassage struct {
+	ID      uint      `json:"id"`                                               // 主键
+	TeamID  uint      `json:"team_id" gorm:"not null;index;comment:队伍ID"`       // 队伍ID
+	Route   uint8     `json:"route" gorm:"not null;comment:路线"`                 // 路线
+	Point   int8      `json:"point" gorm:"not null;comment:点位"`                 // 点位
+	AdminID uint      `json:"admin_id" gorm:"not null;index;comment:扫码管理员ID"`   // 扫码管理员ID
+	Num     uint      `json:"num" gorm:"not null;default:0;comment:经过点位时的队伍人数"` // 经过点位时的队伍人数
+	Time    time.Time `json:"time" gorm:"not null;comment:扫码时间"`                // 扫码时间
+}
+
+func TxInsertPassage(tx *gorm.DB, passage CheckpointPassage) error {
+	return tx.Create(&passage).Error
+}
+
+// GetPassages 按时间顺序获取队伍的全部扫码记录
+func GetPassages(teamID uint) ([]CheckpointPassage, error) {
+	var passages []CheckpointPassage
+	err := global.DB.Where("team_id = ?", teamID).Order("time, id").Find(&passages).Error
+	return passages, err
+}
@@ -7,33 +7,72 @@ import (
 	"gorm.io/gorm"
 	"time"
 	"walk-server/global"
+	"walk-server/privacy"
+	"walk-server/state"
 )
 
 type Person struct {
-	OpenId     string `gorm:"primaryKey;size:64;not null;comment:微信OpenID"` // openID
-	Name       string `gorm:"size:128;not null;comment:姓名"`
-	Gender     int8   `gorm:"not null;comment:性别(1男,2女)"`
-	StuId      string `gorm:"size:32;unique;comment:学号"`
-	Campus     uint8  `gorm:"not null;comment:校区(1朝晖,2屏峰,3莫干山)"`
-	Identity   string `gorm:"size:18;unique;not null;comment:身份证号"`
-	Status     uint8  `gorm:"not null;default:0;comment:队伍状态(0未加入,1队员,2队长)"`
-	Qq         string `gorm:"size:20;comment:QQ号"`
-	Wechat     string `gorm:"size:64;comment:微信号"`
-	College    string `gorm:"size:64;not null;comment:学院"`
-	Tel        string `gorm:"size:20;unique;not null;comment:联系电话"`
-	CreatedOp  uint8  `gorm:"not null;default:3;comment:创建团队次数"`
-	JoinOp     uint8  `gorm:"not null;default:5;comment:加入团队次数"`
-	TeamId     int    `gorm:"index;default:-1;comment:所属团队ID"`
-	Type       uint8  `gorm:"not null;comment:人员类型(1学生,2教职工,3校友)"`
-	WalkStatus uint8  `gorm:"not null;default:1;comment:活动状态(1未开始,2进行中,3扫码成功,4放弃,5完成)"`
+	OpenId     string           `gorm:"primaryKey;size:64;not null;comment:微信OpenID"` // openID
+	Name       string           `gorm:"size:128;not null;comment:姓名"`
+	Gender     int8             `gorm:"not null;comment:性别(1男,2女)"`
+	StuId      string           `gorm:"size:32;unique;comment:学号"`
+	Campus     uint8            `gorm:"not null;comment:校区(1朝晖,2屏峰,3莫干山)"`
+	Identity   string           `gorm:"size:128;not null;serializer:encrypt;comment:身份证号(加密)"`
+	IdIndex    string           `gorm:"column:identity_index;size:64;unique;comment:身份证号盲索引"`
+	Status     uint8            `gorm:"not null;default:0;comment:队伍状态(0未加入,1队员,2队长)"`
+	Qq         string           `gorm:"size:20;comment:QQ号"`
+	Wechat     string           `gorm:"size:64;comment:微信号"`
+	College    string           `gorm:"size:64;not null;comment:学院"`
+	Tel        string           `gorm:"size:20;unique;not null;comment:联系电话"`
+	CreatedOp  uint8            `gorm:"not null;default:3;comment:创建团队次数"`
+	JoinOp     uint8            `gorm:"not null;default:5;comment:加入团队次数"`
+	TeamId     int              `gorm:"index;default:-1;comment:所属团队ID"`
+	Type       uint8            `gorm:"not null;comment:人员类型(1学生,2教职工,3校友)"`
+	WalkStatus state.WalkStatus `gorm:"not null;default:1;comment:活动状态(1未开始,2进行中,3扫码成功,4放弃,5完成)"`
+	Version    uint             `gorm:"not null;default:0;comment:乐观锁版本号"`
 }
 
+// TransitWalk 经过状态机校验后修改队员的毅行状态
+func (p *Person) TransitWalk(next state.WalkStatus) error {
+	if err := p.WalkStatus.Check(next); err != nil {
+		return err
+	}
+	p.WalkStatus = next
+	return nil
+}
+
+// Masked 返回打码后的副本，用于把用户数据直接输出到响应中
+func (p Person) Masked() Person {
+	p.Identity = privacy.MaskIdentity(p.Identity)
+	p.IdIndex = ""
+	p.StuId = privacy.MaskStuID(p.StuId)
+	p.Tel = privacy.MaskTel(p.Tel)
+	return p
+}
+
+// BeforeSave 写入前根据身份证号更新盲索引
+func (p *Person) BeforeSave(tx *gorm.DB) error {
+	if p.Identity != "" {
+		p.IdIndex = privacy.BlindIndex(p.Identity)
+	}
+	return nil
+}
+
+// MarshalBinary 写入缓存时身份证号同样加密保存
 func (p *Person) MarshalBinary() (data []byte, err error) {
-	return json.Marshal(p)
+	cached := *p
+	if cached.Identity, err = privacy.Encrypt(p.Identity); err != nil {
+		return nil, err
+	}
+	return json.Marshal(&cached)
 }
 
-func (p *Person) UnmarshalBinary(data []byte) error {
-	return json.Unmarshal(data, p)
+func (p *Person) UnmarshalBinary(data []byte) (err error) {
+	if err = json.Unmarshal(data, p); err != nil {
+		return err
+	}
+	p.Identity, err = privacy.Decrypt(p.Identity)
+	return err
 }
 
 // encOpenID 是加密后的 openID
@@ -58,15 +97,27 @@ func GetPerson(encOpenID string) (*Person, error) {
 
 // encOpenID 加密后的用户 openID
 // person 用户数据 (完整的)
-// UpdatePerson 更新 person 数据
-func UpdatePerson(encOpenID string, person *Person) {
-	// 如果缓存中存在这个数据, 先更新缓存
+// UpdatePerson 更新 person 数据，写入数据库成功后再更新缓存
+func UpdatePerson(encOpenID string, person *Person) error {
+	// 更新数据库中的数据，同时递增版本号
+	err := global.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where(&Person{OpenId: encOpenID}).Omit("version").Save(person).Error; err != nil {
+			return err
+		}
+		return BumpVersion(tx, &Person{OpenId: encOpenID})
+	})
+	if err != nil {
+		return err
+	}
+
+	// 数据库中的版本号已经递增，缓存中的数据保持一致
+	person.Version++
+
+	// 如果缓存中存在这个数据, 同步更新缓存
 	if _, err := global.Rdb.Get(global.Rctx, encOpenID).Result(); err == nil {
 		global.Rdb.Set(global.Rctx, encOpenID, person, 20*time.Minute)
 	}
-
-	// 更新数据库中的数据
-	global.DB.Where(&Person{OpenId: encOpenID}).Save(person)
+	return nil
 }
 
 func SetPerson(encOpenID string, person *Person) error {
@@ -81,6 +132,9 @@ func SetPerson(encOpenID string, person *Person) error {
 
 // 事务中更新
 func TxUpdatePerson(tx *gorm.DB, person *Person) error {
+	// 数据库中的版本号会递增，缓存中的数据保持一致
+	person.Version++
+
 	// 如果缓存中存在这个数据, 先更新缓存
 	if _, err := global.Rdb.Get(global.Rctx, person.OpenId).Result(); err == nil {
 		global.Rdb.Set(global.Rctx, person.OpenId, person, 20*time.Minute)
@@ -89,9 +143,35 @@ func TxUpdatePerson(tx *gorm.DB, person *Person) error {
 	}
 
 	// 更新数据库中的数据
-	if err := tx.Where(&Person{OpenId: person.OpenId}).Save(person).Error; err != nil {
+	if err := tx.Where(&Person{OpenId: person.OpenId}).Omit("version").Save(person).Error; err != nil {
 		return err
 	}
 
+	// 不检查版本号的写入同样递增版本号，让并发的按版本号更新发现冲突
+	return BumpVersion(tx, &Person{OpenId: person.OpenId})
+}
+
+// TxUpdatePersonWithVersion 在事务中按版本号更新用户，版本号不一致时返回 ErrVersionConflict
+// 不会更新缓存，事务提交后需要调用 ClearPersonCache
+func TxUpdatePersonWithVersion(tx *gorm.DB, person *Person) error {
+	version := person.Version
+	person.Version++
+	result := tx.Model(person).Where("version = ?", version).Select("*").Updates(person)
+	if result.Error != nil {
+		person.Version = version
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		person.Version = version
+		return ErrVersionConflict
+	}
 	return nil
 }
+
+// ClearPersonCache 删除用户缓存，下次读取时从数据库重新加载
+func ClearPersonCache(encOpenIDs ...string) {
+	if len(encOpenIDs) == 0 {
+		return
+	}
+	global.Rdb.Del(global.Rctx, encOpenIDs...)
+}
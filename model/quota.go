@@ -0,0 +1,24 @@
+package model
+
+import "walk-server/global"
+
+// Quota 每天各路线的报名名额，Redis 中只保存剩余名额，总数以数据库为准
+type Quota struct {
+	Day   uint8 `json:"day" gorm:"primaryKey;autoIncrement:false;comment:第几天(从0开始)"`
+	Route uint8 `json:"route" gorm:"primaryKey;autoIncrement:false;comment:路线编号"`
+	Total int   `json:"total" gorm:"not null;comment:名额总数"`
+}
+
+// GetQuotas 获取全部名额，按天数和路线排序
+func GetQuotas() ([]Quota, error) {
+	var quotas []Quota
+	err := global.DB.Order("day, route").Find(&quotas).Error
+	return quotas, err
+}
+
+// GetQuota 获取某天某条路线的名额
+func GetQuota(day uint8, route uint8) (Quota, error) {
+	quota := Quota{Day: day, Route: route}
+	err := global.DB.Where("day = ? AND route = ?", day, route).Take(&quota).Error
+	return quota, err
+}
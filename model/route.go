@@ -0,0 +1,49 @@
+package model
+
+import (
+	"walk-server/global"
+)
+
+type Route struct {
+	ID     uint8  `json:"id" gorm:"primaryKey;autoIncrement:false;comment:路线编号"`
+	Code   string `json:"code" gorm:"size:32;not null;uniqueIndex;comment:路线标识"`
+	Name   string `json:"name" gorm:"size:64;not null;comment:路线名称"`
+	Campus uint8  `json:"campus" gorm:"not null;comment:校区(1朝晖,2屏峰,3莫干山)"`
+}
+
+// Site 实际的打卡地点，多条路线经过同一地点时共用一个 Site
+type Site struct {
+	ID     uint   `json:"id" gorm:"comment:地点ID"`
+	Name   string `json:"name" gorm:"size:64;not null;comment:地点名称"`
+	Campus uint8  `json:"campus" gorm:"not null;comment:校区(1朝晖,2屏峰,3莫干山)"`
+}
+
+// Checkpoint 路线上按顺序排列的点位
+type Checkpoint struct {
+	ID      uint   `json:"id" gorm:"comment:点位ID"`
+	RouteID uint8  `json:"route_id" gorm:"not null;uniqueIndex:idx_route_point;comment:所属路线"`
+	Point   int8   `json:"point" gorm:"not null;uniqueIndex:idx_route_point;comment:点位编号(0起点,最大编号为终点)"`
+	SiteID  uint   `json:"site_id" gorm:"not null;default:0;index;comment:对应地点ID"`
+	Name    string `json:"name" gorm:"size:64;not null;comment:点位名称"`
+}
+
+// GetRoutes 获取全部路线
+func GetRoutes() ([]Route, error) {
+	var routes []Route
+	err := global.DB.Order("id").Find(&routes).Error
+	return routes, err
+}
+
+// GetSites 获取全部地点
+func GetSites() ([]Site, error) {
+	var sites []Site
+	err := global.DB.Order("id").Find(&sites).Error
+	return sites, err
+}
+
+// GetCheckpoints 获取全部点位，按路线和点位编号排序
+func GetCheckpoints() ([]Checkpoint, error) {
+	var checkpoints []Checkpoint
+	err := global.DB.Order("route_id, point").Find(&checkpoints).Error
+	return checkpoints, err
+}
@@ -0,0 +1,40 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"walk-server/privacy"
+
+	"gorm.io/gorm/schema"
+)
+
+func init() {
+	schema.RegisterSerializer("encrypt", EncryptSerializer{})
+}
+
+// EncryptSerializer 写入数据库前加密字符串字段，读取时解密，字段标签为 serializer:encrypt
+type EncryptSerializer struct{}
+
+func (EncryptSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	var value string
+	switch v := dbValue.(type) {
+	case nil:
+	case []byte:
+		value = string(v)
+	case string:
+		value = v
+	default:
+		return fmt.Errorf("无法解密 %T 类型的字段 %s", dbValue, field.Name)
+	}
+	plain, err := privacy.Decrypt(value)
+	if err != nil {
+		return err
+	}
+	return field.Set(ctx, dst, plain)
+}
+
+func (EncryptSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	value, _ := fieldValue.(string)
+	return privacy.Encrypt(value)
+}
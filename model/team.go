@@ -4,23 +4,40 @@ import (
 	"errors"
 	"time"
 	"walk-server/global"
+	"walk-server/state"
+
+	"gorm.io/gorm"
 )
 
 type Team struct {
-	ID         uint      `gorm:"comment:队伍ID"`
-	Name       string    `gorm:"size:64;not null;comment:队伍名称"`
-	Num        uint8     `gorm:"not null;default:1;comment:团队人数"`
-	Password   string    `gorm:"size:64;not null;comment:团队加入密码"`
-	Slogan     string    `gorm:"size:128;comment:团队标语"`
-	AllowMatch bool      `gorm:"not null;default:false;comment:是否允许随机匹配"`
-	Captain    string    `gorm:"size:64;not null;comment:队长OpenID"`
-	Route      uint8     `gorm:"not null;comment:路线(1朝晖,2屏峰半程,3屏峰全程,4莫干山半程,5莫干山全程)"`
-	Point      int8      `gorm:"default:0;comment:点位"`
-	StartNum   uint      `gorm:"not null;default:0;comment:开始时人数"`
-	Status     uint8     `gorm:"not null;default:1;comment:状态(1未开始,2进行中,3未完成,4完成,5扫码成功)"`
-	Submit     bool      `gorm:"not null;default:false;comment:是否已提交报名"`
-	Code       string    `gorm:"size:128;index;comment:签到二维码绑定码"`
-	Time       time.Time `gorm:"comment:队伍状态更新时间"`
+	ID         uint             `gorm:"comment:队伍ID"`
+	Name       string           `gorm:"size:64;not null;comment:队伍名称"`
+	Num        uint8            `gorm:"not null;default:1;comment:团队人数"`
+	Password   string           `gorm:"size:64;not null;comment:团队加入密码"`
+	Slogan     string           `gorm:"size:128;comment:团队标语"`
+	AllowMatch bool             `gorm:"not null;default:false;comment:是否允许随机匹配"`
+	Captain    string           `gorm:"size:64;not null;comment:队长OpenID"`
+	Route      uint8            `gorm:"not null;comment:路线(1朝晖,2屏峰半程,3屏峰全程,4莫干山半程,5莫干山全程)"`
+	Point      int8             `gorm:"default:0;comment:点位"`
+	StartNum   uint             `gorm:"not null;default:0;comment:开始时人数"`
+	Status     state.TeamStatus `gorm:"not null;default:1;comment:状态(1未开始,2进行中,3未完成,4完成,5扫码成功)"`
+	Submit     bool             `gorm:"not null;default:false;comment:是否已提交报名"`
+	SubmitDay  *uint8           `gorm:"comment:提交时占用名额的日期(管理员提交的队伍为空)"`
+	Code       string           `gorm:"size:128;index;comment:签到二维码绑定码"`
+	Time       time.Time        `gorm:"comment:队伍状态更新时间"`
+	Version    uint             `gorm:"not null;default:0;comment:乐观锁版本号"`
+}
+
+// ErrVersionConflict 按版本号更新时数据已经被其他请求修改
+var ErrVersionConflict = errors.New("version conflict")
+
+// Transit 经过状态机校验后修改队伍状态
+func (t *Team) Transit(next state.TeamStatus) error {
+	if err := t.Status.Check(next); err != nil {
+		return err
+	}
+	t.Status = next
+	return nil
 }
 
 func GetTeamInfo(teamID uint) (*Team, error) {
@@ -34,12 +51,20 @@ func GetTeamInfo(teamID uint) (*Team, error) {
 }
 
 func GetPersonsInTeam(teamID int) (Person, []Person) {
+	captain, members, _ := TxGetPersonsInTeam(global.DB, teamID)
+	return captain, members
+}
+
+// TxGetPersonsInTeam 在事务中获取队伍的队长和队员
+func TxGetPersonsInTeam(tx *gorm.DB, teamID int) (Person, []Person, error) {
 	var persons []Person
 
 	var captain Person
 	var members []Person
 
-	global.DB.Where("team_id = ?", teamID).Find(&persons)
+	if err := tx.Where("team_id = ?", teamID).Find(&persons).Error; err != nil {
+		return captain, members, err
+	}
 	for _, person := range persons {
 		if person.Status == 2 { // 队长
 			captain = person
@@ -48,5 +73,26 @@ func GetPersonsInTeam(teamID int) (Person, []Person) {
 		}
 	}
 
-	return captain, members
+	return captain, members, nil
+}
+
+// TxUpdateTeam 在事务中按版本号更新队伍，版本号不一致时返回 ErrVersionConflict
+func TxUpdateTeam(tx *gorm.DB, team *Team) error {
+	version := team.Version
+	team.Version++
+	result := tx.Model(team).Where("version = ?", version).Select("*").Updates(team)
+	if result.Error != nil {
+		team.Version = version
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		team.Version = version
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+// BumpVersion 递增版本号，不按版本号更新的写入调用，value 需要带有主键
+func BumpVersion(tx *gorm.DB, value any) error {
+	return tx.Model(value).UpdateColumn("version", gorm.Expr("version + 1")).Error
 }
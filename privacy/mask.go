@@ -0,0 +1,37 @@
+package privacy
+
+// mask 保留前 head 位和后 tail 位，中间替换为 *
+func mask(value string, head int, tail int) string {
+	runes := []rune(value)
+	if len(runes) <= head+tail {
+		if len(runes) <= 1 {
+			return value
+		}
+		// 太短时只保留第一位
+		head, tail = 1, 0
+	}
+	masked := make([]rune, len(runes))
+	for i, r := range runes {
+		if i < head || i >= len(runes)-tail {
+			masked[i] = r
+		} else {
+			masked[i] = '*'
+		}
+	}
+	return string(masked)
+}
+
+// MaskTel 手机号打码，如 138****1234
+func MaskTel(tel string) string {
+	return mask(tel, 3, 4)
+}
+
+// MaskIdentity 身份证号打码，只保留前 3 位和后 4 位
+func MaskIdentity(id string) string {
+	return mask(id, 3, 4)
+}
+
+// MaskStuID 学号打码，只保留前 4 位和后 2 位
+func MaskStuID(stuID string) string {
+	return mask(stuID, 4, 2)
+}
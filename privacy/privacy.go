@@ -0,0 +1,91 @@
+// Package privacy 负责个人信息的加密、盲索引和打码
+// 身份证号加密后保存，需要按身份证号查询时使用盲索引
+package privacy
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+// 加密后的值带有前缀，没有前缀的视为迁移前的明文
+const encryptedPrefix = "enc:"
+
+var ErrNotInitialized = errors.New("个人信息密钥未初始化")
+
+var (
+	aead     cipher.AEAD
+	indexKey []byte
+)
+
+// Init 根据配置的密钥派生加密密钥和盲索引密钥
+func Init(key string) error {
+	encKey := sha256.Sum256([]byte("walk-server encrypt:" + key))
+	block, err := aes.NewCipher(encKey[:])
+	if err != nil {
+		return err
+	}
+	aead, err = cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+	mac := sha256.Sum256([]byte("walk-server index:" + key))
+	indexKey = mac[:]
+	return nil
+}
+
+// IsEncrypted 判断值是否已经加密
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix)
+}
+
+// Encrypt 使用 AES-GCM 加密，每次加密使用随机 nonce，空值不加密
+func Encrypt(plain string) (string, error) {
+	if plain == "" || IsEncrypted(plain) {
+		return plain, nil
+	}
+	if aead == nil {
+		return "", ErrNotInitialized
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plain), nil)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt 解密 Encrypt 的结果，没有加密前缀的值原样返回
+func Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	if aead == nil {
+		return "", ErrNotInitialized
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedPrefix))
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", errors.New("密文长度错误")
+	}
+	plain, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+// BlindIndex 计算用于等值查询和唯一约束的盲索引
+func BlindIndex(value string) string {
+	mac := hmac.New(sha256.New, indexKey)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
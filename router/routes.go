@@ -9,12 +9,13 @@ import (
 	"walk-server/controller/team"
 	"walk-server/controller/user"
 	"walk-server/middleware"
+	"walk-server/model"
 
 	"github.com/gin-gonic/gin"
 )
 
 func MountRoutes(router *gin.Engine) {
-	router.POST("/api/v1/redis2mysql", middleware.TokenRateLimiter, team.RedisToMysql) // 从 Redis 中导入数据到 MySQL
+	router.GET("/api/v1/file/:id", middleware.TokenRateLimiter, basic.DownloadFile) // 通过签名链接下载生成的文件
 	api := router.Group("/api/v1", middleware.TokenRateLimiter)
 	{
 		if !gin.IsDebugging() {
@@ -22,9 +23,13 @@ func MountRoutes(router *gin.Engine) {
 		}
 
 		// Basic
-		api.GET("/oauth", basic.Oauth)                // 微信 Oauth 的起点接口
-		api.GET("/login", basic.Login)                // 微信服务器的回调地址
-		api.GET("/login/openid", basic.LoginByOpenID) // 通过 openID 登录
+		api.GET("/oauth", basic.Oauth)                 // 微信 Oauth 的起点接口
+		api.GET("/login", basic.Login)                 // 微信服务器的回调地址
+		api.POST("/login/ticket", basic.LoginByTicket) // 使用一次性票据登录
+		api.POST("/token/refresh", basic.RefreshToken) // 刷新登录凭证
+		if gin.IsDebugging() {
+			api.GET("/login/openid", basic.LoginByOpenID) // 通过 openID 登录，仅调试模式
+		}
 
 		// Register
 		registerApi := api.Group("/register", middleware.RegisterJWTValidity, middleware.PerRateLimiter)
@@ -39,6 +44,10 @@ func MountRoutes(router *gin.Engine) {
 		{
 			userApi.GET("/info", user.GetInfo)                             // 获取用户信息
 			userApi.POST("/modify", middleware.IsExpired, user.ModifyInfo) // 修改用户信息
+			userApi.GET("/export", user.ExportData)                        // 导出本人的全部数据
+			userApi.POST("/withdraw", user.Withdraw)                       // 退出活动并注销
+			userApi.GET("/sessions", user.GetSessions)                     // 获取本人的登录会话
+			userApi.POST("/sessions/revoke", user.RevokeSession)           // 退出登录或撤销其他会话
 		}
 
 		// Team
@@ -61,6 +70,14 @@ func MountRoutes(router *gin.Engine) {
 			teamApi.GET("/disband", middleware.IsExpired, team.DisbandTeam)     // 解散团队
 			teamApi.GET("/rollback", middleware.IsExpired, team.RollBackTeam)   // 撤销提交
 			teamApi.POST("/captain", middleware.IsExpired, team.ChangeCaptain)  // 更换队长
+
+			// 入队申请，队长同意后加入
+			teamApi.POST("/request", middleware.IsExpired, team.RequestJoin)                // 申请加入团队
+			teamApi.GET("/requests", team.GetJoinRequests)                                  // 队长查看待处理的申请
+			teamApi.GET("/request/mine", team.GetMyJoinRequests)                            // 查看本人的申请
+			teamApi.POST("/request/approve", middleware.IsExpired, team.ApproveJoinRequest) // 同意申请
+			teamApi.POST("/request/reject", middleware.IsExpired, team.RejectJoinRequest)   // 拒绝申请
+			teamApi.POST("/request/cancel", middleware.IsExpired, team.CancelJoinRequest)   // 撤回申请
 		}
 
 		// 事件相关的 API
@@ -77,30 +94,70 @@ func MountRoutes(router *gin.Engine) {
 		}
 	}
 
+	// 管理端按角色划分权限
+	scan := middleware.RequireRole(model.RoleSuper, model.RoleRouteLead, model.RoleVolunteer)
+	lead := middleware.RequireRole(model.RoleSuper, model.RoleRouteLead)
+	view := middleware.RequireRole(model.RoleSuper, model.RoleRouteLead, model.RoleObserver)
+	super := middleware.RequireRole(model.RoleSuper)
+
 	adminApi := router.Group("/api/v1/admin", middleware.TokenRateLimiter)
 	{
-		adminApi.POST("/auth", admin.AuthByPassword)                                     // 微信登录
-		adminApi.POST("/auth/auto", admin.WeChatLogin)                                   // 自动登录
-		adminApi.POST("/auth/without", admin.AuthWithoutCode)                            // 测试登录
-		adminApi.GET("/team/status", middleware.CheckAdmin, admin.GetTeam)               // 获取队伍信息
-		adminApi.POST("/team/user_status", middleware.CheckAdmin, admin.UserStatus)      // 更新用户状态
-		adminApi.POST("/team/bind", middleware.CheckAdmin, admin.BindTeam)               // 绑定队伍
-		adminApi.POST("/team/update", middleware.CheckAdmin, admin.UpdateTeamStatus)     // 更新队伍状态
-		adminApi.POST("/team/destination", middleware.CheckAdmin, admin.PostDestination) // 提交终点
-		adminApi.POST("/team/secret", admin.BlockWithSecret)                             // 通过密钥封禁接口
-		adminApi.POST("/team/regroup", middleware.CheckAdmin, admin.Regroup)             // 重新分组
-		adminApi.POST("/team/submit", middleware.CheckAdmin, admin.SubmitTeam)           // 提交团队
-		adminApi.GET("/detail", admin.GetDetail)                                         // 获取路线人员详情
-		adminApi.GET("/submit", admin.GetSubmitDetail)                                   // 获取报名人员列表
-		adminApi.GET("/timeout", admin.GetTimeoutUsers)                                  // 获取超时未提交的用户
-		adminApi.GET("/timeout/download", admin.DownloadTimeoutUsers)                    // 下载超时未提交的用户
-		adminApi.GET("/team/status/secret", admin.GetTeamBySecret)                       // 通过密钥获取队伍信息
-		adminApi.POST("/route/create", admin.CreateRouteAdmin)                           // 创建路线管理员
+		adminApi.POST("/auth", admin.AuthByPassword)          // 微信登录
+		adminApi.POST("/auth/auto", admin.WeChatLogin)        // 自动登录
+		adminApi.POST("/auth/without", admin.AuthWithoutCode) // 测试登录
+		adminApi.POST("/credential", admin.GetCredential)     // 通过一次性凭证查看账号密码
+		adminApi.POST("/auth/refresh", admin.RefreshToken)    // 刷新登录凭证
+
+		adminApi.GET("/team/status", middleware.CheckAdmin, scan, admin.GetTeam)               // 获取队伍信息
+		adminApi.POST("/team/user_status", middleware.CheckAdmin, scan, admin.UserStatus)      // 更新用户状态
+		adminApi.POST("/team/bind", middleware.CheckAdmin, scan, admin.BindTeam)               // 绑定队伍
+		adminApi.POST("/team/update", middleware.CheckAdmin, scan, admin.UpdateTeamStatus)     // 更新队伍状态
+		adminApi.POST("/team/destination", middleware.CheckAdmin, scan, admin.PostDestination) // 提交终点
+		adminApi.GET("/team/timeline", middleware.CheckAdmin, scan, admin.GetTeamTimeline)     // 获取队伍各点位时间线
+		adminApi.POST("/team/secret", middleware.CheckAdmin, lead, admin.BlockWithSecret)      // 校验负责人权限
+		adminApi.POST("/team/regroup", middleware.CheckAdmin, lead, admin.Regroup)             // 重新分组
+		adminApi.POST("/team/submit", middleware.CheckAdmin, lead, admin.SubmitTeam)           // 提交团队
+
+		adminApi.GET("/detail", middleware.CheckAdmin, view, admin.GetDetail)                            // 获取路线人员详情
+		adminApi.GET("/detail/stream/token", middleware.CheckAdmin, view, admin.GetStreamToken)          // 获取大屏推送的短期凭证
+		adminApi.GET("/detail/stream", middleware.CheckStreamToken, view, admin.DashboardStream)         // 实时推送路线人员详情和扫码事件
+		adminApi.POST("/detail/rebuild", middleware.CheckAdmin, super, admin.RebuildDashboard)           // 根据数据库重新统计路线人员详情
+		adminApi.GET("/submit", middleware.CheckAdmin, view, admin.GetSubmitDetail)                      // 获取报名人员列表
+		adminApi.GET("/timeout", middleware.CheckAdmin, view, admin.GetTimeoutUsers)                     // 获取超时未提交的用户
+		adminApi.GET("/timeout/download", middleware.CheckAdmin, view, admin.DownloadTimeoutUsers)       // 下载超时未提交的用户
+		adminApi.GET("/team/status/secret", middleware.CheckAdmin, view, admin.GetTeamBySecret)          // 获取任意队伍信息
+		adminApi.GET("/anomaly/list", middleware.CheckAdmin, view, admin.GetAnomalies)                   // 获取扫码顺序异常列表
+		adminApi.POST("/anomaly/review", middleware.CheckAdmin, lead, admin.ReviewAnomaly)               // 复核扫码顺序异常
+		adminApi.GET("/audit/list", middleware.CheckAdmin, lead, admin.GetAuditLogs)                     // 查询审计日志
+		adminApi.GET("/user/export", middleware.CheckAdmin, lead, admin.ExportUsers)                     // 导出队伍和队员名单
+		adminApi.POST("/user/import", middleware.CheckAdmin, super, admin.ImportUsers)                   // 批量导入教职工和校友名单
+		adminApi.POST("/user/reveal", middleware.CheckAdmin, scan, admin.RevealPerson)                   // 查看队员完整的个人信息
+		adminApi.GET("/user/sessions", middleware.CheckAdmin, lead, admin.GetUserSessions)               // 获取队员的登录会话
+		adminApi.POST("/user/sessions/revoke", middleware.CheckAdmin, lead, admin.RevokeUserSessions)    // 撤销队员的登录会话
+		adminApi.POST("/route/create", middleware.CheckAdmin, super, admin.CreateRouteAdmin)             // 创建路线管理员
+		adminApi.POST("/account/role", middleware.CheckAdmin, super, admin.SetAdminRole)                 // 修改管理员角色
+		adminApi.POST("/account/password", middleware.CheckAdminAllowMustChange, admin.ChangePassword)   // 修改自己的密码
+		adminApi.POST("/account/password/reset", middleware.CheckAdmin, lead, admin.ResetPassword)       // 重置管理员密码
+		adminApi.POST("/account/password/rotate", middleware.CheckAdmin, super, admin.RotatePasswords)   // 轮换路线志愿者密码
+		adminApi.POST("/account/password/force", middleware.CheckAdmin, lead, admin.ForcePasswordChange) // 要求管理员修改密码
+		adminApi.GET("/account/sessions", middleware.CheckAdmin, admin.GetAdminSessions)                 // 获取自己或下属管理员的登录会话
+		adminApi.POST("/account/sessions/revoke", middleware.CheckAdmin, admin.RevokeAdminSessions)      // 撤销自己或下属管理员的登录会话
+		adminApi.GET("/reconcile/report", middleware.CheckAdmin, view, admin.GetReconcileReport)         // 获取提交状态比对报告
+		adminApi.POST("/reconcile/run", middleware.CheckAdmin, super, admin.RunReconcile)                // 立即比对并修复提交状态
+		adminApi.GET("/quota/list", middleware.CheckAdmin, view, admin.GetQuotas)                        // 获取每天各路线的报名名额
+		adminApi.POST("/quota/save", middleware.CheckAdmin, super, admin.SaveQuota)                      // 修改报名名额
+		adminApi.GET("/route/list", middleware.CheckAdmin, view, admin.GetRoutes)                        // 获取路线和点位
+		adminApi.POST("/route/save", middleware.CheckAdmin, super, admin.SaveRoute)                      // 新增或修改路线
+		adminApi.POST("/route/delete", middleware.CheckAdmin, super, admin.DeleteRoute)                  // 删除路线
+		adminApi.POST("/route/site/save", middleware.CheckAdmin, super, admin.SaveSite)                  // 新增或修改地点
+		adminApi.POST("/route/site/delete", middleware.CheckAdmin, super, admin.DeleteSite)              // 删除地点
+		adminApi.POST("/route/point/save", middleware.CheckAdmin, super, admin.SaveCheckpoint)           // 新增或修改点位
+		adminApi.POST("/route/point/delete", middleware.CheckAdmin, super, admin.DeleteCheckpoint)       // 删除点位
 
 		if gin.IsDebugging() {
-			adminApi.POST("/test/create", admin.CreateTestTeams) // 创建测试队伍
-			adminApi.POST("/test/delete", admin.DeleteTestTeams) // 删除测试队伍
-			adminApi.POST("/test/update", admin.UpdateTestTeams) // 更新测试队伍
+			adminApi.POST("/test/create", middleware.CheckAdmin, super, admin.CreateTestTeams) // 创建测试队伍
+			adminApi.POST("/test/delete", middleware.CheckAdmin, super, admin.DeleteTestTeams) // 删除测试队伍
+			adminApi.POST("/test/update", middleware.CheckAdmin, super, admin.UpdateTestTeams) // 更新测试队伍
 		}
 	}
 }
@@ -0,0 +1,113 @@
+package adminService
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+	"walk-server/global"
+	"walk-server/model"
+	"walk-server/utility"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 新生成的密码只通过一次性凭证查看，数据库中只保存哈希
+// Redis 中的凭证用由凭证派生的密钥加密，键名是凭证的另一个派生值，只有持有凭证的人能解密
+const (
+	credentialKeyPrefix = "credential:"
+	credentialExpire    = 24 * time.Hour
+)
+
+var ErrCredentialNotFound = errors.New("凭证不存在或已被查看")
+
+type Credential struct {
+	Name     string `json:"name"`
+	Account  string `json:"account"`
+	Password string `json:"password"`
+}
+
+// deriveCredential 从凭证派生 Redis 键名和加密密钥
+func deriveCredential(token string) (string, []byte) {
+	id := sha256.Sum256([]byte("credential:id:" + token))
+	key := sha256.Sum256([]byte("credential:key:" + token))
+	return credentialKeyPrefix + hex.EncodeToString(id[:]), key[:]
+}
+
+func credentialCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// IssueCredential 加密保存新密码并返回一次性查看凭证
+func IssueCredential(admin model.Admin, password string) (string, error) {
+	token, err := utility.RandomToken(16)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(Credential{
+		Name:     admin.Name,
+		Account:  admin.Account,
+		Password: password,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	redisKey, key := deriveCredential(token)
+	aead, err := credentialCipher(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, data, nil)
+	if err := global.Rdb.Set(global.Rctx, redisKey, sealed, credentialExpire).Err(); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// DiscardCredential 作废还没有被查看的凭证
+func DiscardCredential(token string) error {
+	redisKey, _ := deriveCredential(token)
+	return global.Rdb.Del(global.Rctx, redisKey).Err()
+}
+
+// TakeCredential 解密凭证中的密码，读取后凭证立即失效
+func TakeCredential(token string) (*Credential, error) {
+	redisKey, key := deriveCredential(token)
+	sealed, err := global.Rdb.GetDel(global.Rctx, redisKey).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrCredentialNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	aead, err := credentialCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, ErrCredentialNotFound
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	data, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, ErrCredentialNotFound
+	}
+	var credential Credential
+	if err := json.Unmarshal(data, &credential); err != nil {
+		return nil, err
+	}
+	return &credential, nil
+}
@@ -2,10 +2,10 @@ package adminService
 
 import (
 	"github.com/gin-gonic/gin"
-	"strconv"
 	"time"
 	"walk-server/global"
 	"walk-server/model"
+	"walk-server/state"
 	"walk-server/utility"
 )
 
@@ -51,12 +51,35 @@ func GetAdminByID(id uint) (*model.Admin, error) {
 	return &user, nil
 }
 
+func GetAdminsByIDs(ids []uint) (map[uint]model.Admin, error) {
+	var admins []model.Admin
+	if err := global.DB.Where("id IN ?", ids).Find(&admins).Error; err != nil {
+		return nil, err
+	}
+
+	adminMap := make(map[uint]model.Admin, len(admins))
+	for _, admin := range admins {
+		adminMap[admin.ID] = admin
+	}
+	return adminMap, nil
+}
+
+func GetAdminsByRoute(route uint8, role uint8) ([]model.Admin, error) {
+	var admins []model.Admin
+	err := global.DB.Where("route = ? AND role = ?", route, role).Find(&admins).Error
+	return admins, err
+}
+
+// GetAdminByJWT 获取当前请求的管理员，CheckAdmin 已经读取过时直接使用
 func GetAdminByJWT(context *gin.Context) (*model.Admin, error) {
+	if admin := utility.GetAdmin(context); admin != nil {
+		return admin, nil
+	}
 	jwtData := utility.GetJwtData(context)
-	// jwt token 解析失败
-	userID := utility.AesDecrypt(jwtData.OpenID, global.Config.GetString("server.AESSecret"))
-	user_id, err := strconv.Atoi(userID)
-	user, err := GetAdminByID(uint(user_id))
+	if jwtData == nil || jwtData.Kind != utility.KindAdmin {
+		return nil, utility.ErrTokenKind
+	}
+	user, err := GetAdminByID(jwtData.AdminID)
 	if err != nil {
 		return nil, err
 	}
@@ -66,7 +89,7 @@ func GetAdminByJWT(context *gin.Context) (*model.Admin, error) {
 func GetTimeoutTeams(min int, route uint8) (map[int8][]model.Team, error) {
 	var teams []model.Team
 	duration := time.Duration(min) * time.Minute
-	result := global.DB.Where("time < ? And route = ?", time.Now().Add(-duration), route).Not("status = 4").Not("status = 1").Find(&teams)
+	result := global.DB.Where("time < ? And route = ?", time.Now().Add(-duration), route).Not("status = ?", state.TeamFinished).Not("status = ?", state.TeamNotStarted).Find(&teams)
 	if result.Error != nil {
 		return nil, result.Error
 	}
@@ -81,6 +104,6 @@ func GetTimeoutTeams(min int, route uint8) (map[int8][]model.Team, error) {
 
 func GetNoShowTeams(route uint8) ([]model.Team, error) {
 	var teams []model.Team
-	result := global.DB.Where("status = 1 And route = ?", route).Find(&teams)
+	result := global.DB.Where("status = ? And route = ?", state.TeamNotStarted, route).Find(&teams)
 	return teams, result.Error
 }
@@ -3,8 +3,42 @@ package adminService
 import (
 	"walk-server/global"
 	"walk-server/model"
+
+	"gorm.io/gorm"
 )
 
 func UpdateOpenID(admin model.Admin) {
 	global.DB.Updates(&admin)
 }
+
+func UpdateRole(id uint, role uint8) error {
+	return global.DB.Model(&model.Admin{}).Where("id = ?", id).Update("role", role).Error
+}
+
+// UpdatePassword 保存新的密码哈希，mustChange 表示下次登录时需要修改密码
+func UpdatePassword(id uint, hash string, mustChange bool) error {
+	return global.DB.Model(&model.Admin{}).Where("id = ?", id).Updates(map[string]any{
+		"password":    hash,
+		"must_change": mustChange,
+	}).Error
+}
+
+// UpdatePasswords 在一个事务中保存多个管理员的新密码哈希
+func UpdatePasswords(hashes map[uint]string, mustChange bool) error {
+	return global.DB.Transaction(func(tx *gorm.DB) error {
+		for id, hash := range hashes {
+			err := tx.Model(&model.Admin{}).Where("id = ?", id).Updates(map[string]any{
+				"password":    hash,
+				"must_change": mustChange,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func ForcePasswordChange(id uint) error {
+	return global.DB.Model(&model.Admin{}).Where("id = ?", id).Update("must_change", true).Error
+}
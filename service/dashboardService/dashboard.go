@@ -0,0 +1,189 @@
+package dashboardService
+
+import (
+	"encoding/json"
+	"log"
+	"strconv"
+	"time"
+	"walk-server/constant"
+	"walk-server/global"
+	"walk-server/model"
+	"walk-server/state"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 看板计数保存在 Redis 中，每条路线一个 hash，扫码时增量更新，避免大屏轮询时反复统计 MySQL
+const (
+	countKeyPrefix = "dashboard:route:"
+	recentKey      = "dashboard:recent"
+	EventChannel   = "dashboard:events"
+	recentLimit    = 50
+
+	FieldStart   = "start"   // 未开始
+	FieldFinish  = "finish"  // 已结束
+	FieldAbandon = "abandon" // 下撤
+)
+
+// Event 推送给大屏的扫码事件
+type Event struct {
+	Type      string    `json:"type"` // bind 起点扫码，scan 点位扫码，finish 到达终点，incomplete 终点未完成，confirm 确认到场，abandon 下撤
+	TeamID    uint      `json:"team_id"`
+	TeamName  string    `json:"team_name"`
+	Route     uint8     `json:"route"`
+	Point     int8      `json:"point"`
+	PointName string    `json:"point_name"`
+	Num       uint      `json:"num"`
+	Admin     string    `json:"admin"`
+	Time      time.Time `json:"time"`
+}
+
+// Counts 看板计数，路线编号 -> 计数项 -> 人数
+type Counts map[uint8]map[string]int64
+
+// PointField 返回点位对应的计数项
+func PointField(point int8) string {
+	return strconv.Itoa(int(point))
+}
+
+// Add 把一支队伍计入看板，统计规则与 GetDetail 原来的数据库统计一致
+func (c Counts) Add(team *model.Team, persons []model.Person) {
+	if c[team.Route] == nil {
+		c[team.Route] = make(map[string]int64)
+	}
+	counts := c[team.Route]
+	onRoad := team.Status == state.TeamStarted || team.Status == state.TeamInProgress
+	for _, p := range persons {
+		switch {
+		case p.WalkStatus == state.WalkNotStarted && team.Submit:
+			counts[FieldStart]++
+		case p.WalkStatus.IsWalking() && onRoad && team.Point >= 0:
+			counts[PointField(team.Point)]++
+		case p.WalkStatus == state.WalkFinished:
+			counts[FieldFinish]++
+		case p.WalkStatus == state.WalkAbandoned:
+			counts[FieldAbandon]++
+		}
+	}
+}
+
+func countKey(route uint8) string {
+	return countKeyPrefix + strconv.Itoa(int(route))
+}
+
+// Apply 把两次统计之间的差值增量写入 Redis
+func Apply(before Counts, after Counts) error {
+	pipe := global.Rdb.Pipeline()
+	diff := func(route uint8, field string) {
+		if delta := after[route][field] - before[route][field]; delta != 0 {
+			pipe.HIncrBy(global.Rctx, countKey(route), field, delta)
+		}
+	}
+	for route, counts := range after {
+		for field := range counts {
+			diff(route, field)
+		}
+	}
+	for route, counts := range before {
+		for field := range counts {
+			if _, ok := after[route][field]; !ok {
+				diff(route, field)
+			}
+		}
+	}
+	_, err := pipe.Exec(global.Rctx)
+	return err
+}
+
+// Sync 在写入数据库之后更新看板计数，更新失败时记录日志并从 MySQL 重新统计
+func Sync(before Counts, after Counts) {
+	if err := Apply(before, after); err != nil {
+		log.Println("看板计数更新失败: ", err)
+		if err := Rebuild(); err != nil {
+			log.Println("看板计数重新统计失败: ", err)
+		}
+	}
+}
+
+// Rebuild 从 MySQL 重新统计全部路线的看板计数
+func Rebuild() error {
+	var teams []model.Team
+	if err := global.DB.Find(&teams).Error; err != nil {
+		return err
+	}
+	var persons []model.Person
+	if err := global.DB.Where("team_id <> -1").Find(&persons).Error; err != nil {
+		return err
+	}
+
+	members := make(map[int][]model.Person)
+	for _, p := range persons {
+		members[p.TeamId] = append(members[p.TeamId], p)
+	}
+	counts := make(Counts)
+	for i := range teams {
+		counts.Add(&teams[i], members[int(teams[i].ID)])
+	}
+
+	pipe := global.Rdb.TxPipeline()
+	for _, route := range constant.GetRoutes() {
+		pipe.Del(global.Rctx, countKey(route.ID))
+		for field, count := range counts[route.ID] {
+			pipe.HSet(global.Rctx, countKey(route.ID), field, count)
+		}
+	}
+	_, err := pipe.Exec(global.Rctx)
+	return err
+}
+
+// GetCounts 读取一条路线的看板计数
+func GetCounts(route uint8) (map[string]int64, error) {
+	values, err := global.Rdb.HGetAll(global.Rctx, countKey(route)).Result()
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int64, len(values))
+	for field, value := range values {
+		counts[field], _ = strconv.ParseInt(value, 10, 64)
+	}
+	return counts, nil
+}
+
+// Publish 记录最近的扫码事件并通知所有订阅的大屏
+func Publish(event Event) error {
+	if event.PointName == "" {
+		event.PointName = constant.GetPointName(event.Route, event.Point)
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	pipe := global.Rdb.Pipeline()
+	pipe.LPush(global.Rctx, recentKey, data)
+	pipe.LTrim(global.Rctx, recentKey, 0, recentLimit-1)
+	pipe.Publish(global.Rctx, EventChannel, data)
+	_, err = pipe.Exec(global.Rctx)
+	return err
+}
+
+// GetRecentEvents 获取最近的扫码事件，最新的在前
+func GetRecentEvents() ([]Event, error) {
+	values, err := global.Rdb.LRange(global.Rctx, recentKey, 0, recentLimit-1).Result()
+	if err != nil {
+		return nil, err
+	}
+	events := make([]Event, 0, len(values))
+	for _, value := range values {
+		var event Event
+		if json.Unmarshal([]byte(value), &event) == nil {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+// Subscribe 订阅扫码事件，使用完毕后需要关闭
+func Subscribe() *redis.PubSub {
+	return global.Rdb.Subscribe(global.Rctx, EventChannel)
+}
@@ -0,0 +1,53 @@
+package identityService
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/zjutjh/WeJH-SDK/oauth"
+	"github.com/zjutjh/WeJH-SDK/oauth/oauthException"
+)
+
+// Campus 通过学校统一身份认证验证学号和密码
+type Campus struct{}
+
+// getUserInfo 请求统一验证，测试时替换
+var getUserInfo = oauth.GetUserInfo
+
+func (Campus) Verify(cred Credential) (*Identity, error) {
+	if strings.TrimSpace(cred.Account) == "" || cred.Password == "" {
+		return nil, ErrWrongCredential
+	}
+	cookie, info, err := getUserInfo(cred.Account, cred.Password)
+	var oauthErr *oauthException.Error
+	if errors.As(err, &oauthErr) {
+		switch {
+		case errors.Is(oauthErr, oauthException.WrongAccount), errors.Is(oauthErr, oauthException.WrongPassword):
+			return nil, ErrWrongCredential
+		case errors.Is(oauthErr, oauthException.ClosedError):
+			return nil, ErrClosed
+		case errors.Is(oauthErr, oauthException.NotActivatedError):
+			return nil, ErrNotActivated
+		}
+		return nil, err
+	} else if err != nil {
+		return nil, err
+	}
+	if cookie == nil {
+		return nil, ErrVerifyFailed
+	}
+
+	identity := &Identity{
+		Name:    info.Name,
+		Gender:  2,
+		College: info.College,
+		Type:    TypeStudent,
+	}
+	if info.Gender == "male" {
+		identity.Gender = 1
+	}
+	if info.UserTypeDesc == "教师职工" || info.UserTypeDesc == "人才派遣" {
+		identity.Type = TypeTeacher
+	}
+	return identity, nil
+}
@@ -0,0 +1,90 @@
+package identityService
+
+import (
+	"errors"
+	"fmt"
+)
+
+// 人员类型，与 model.Person.Type 一致
+const (
+	TypeStudent uint8 = 1
+	TypeTeacher uint8 = 2
+	TypeAlumnus uint8 = 3
+)
+
+// validType 是否为可以报名的人员类型
+func validType(personType uint8) bool {
+	return personType == TypeStudent || personType == TypeTeacher || personType == TypeAlumnus
+}
+
+// 身份验证失败的原因，错误信息可以直接返回给用户
+var (
+	ErrWrongCredential = errors.New("账号或密码错误")
+	ErrClosed          = errors.New("统一夜间关闭，请白天尝试")
+	ErrNotActivated    = errors.New("账号未激活，请自行到统一网站重新激活")
+	ErrVerifyFailed    = errors.New("统一验证失败，请稍后再试")
+	ErrNotInRoster     = errors.New("名单中没有您的信息，请检查是否填写有误")
+	ErrNoProvider      = errors.New("暂未开放该类人员报名")
+)
+
+// Credential 报名时提交的身份凭证
+type Credential struct {
+	Type     uint8  // 报名的人员类型
+	Account  string // 学号或工号
+	Password string
+	Identity string // 身份证号
+}
+
+// Identity 身份验证通过后得到的个人信息
+type Identity struct {
+	Name    string
+	Gender  int8 // 1 男，2 女
+	College string
+	Type    uint8 // 实际的人员类型
+}
+
+// IdentityProvider 报名时的身份验证方式
+type IdentityProvider interface {
+	Verify(cred Credential) (*Identity, error)
+}
+
+var (
+	providers = map[uint8]IdentityProvider{}
+	fallback  IdentityProvider
+)
+
+// Register 设置某类人员使用的身份验证方式
+func Register(personType uint8, provider IdentityProvider) {
+	providers[personType] = provider
+}
+
+// SetFallback 设置统一验证关闭时改用的身份验证方式
+func SetFallback(provider IdentityProvider) {
+	fallback = provider
+}
+
+// NewProvider 根据配置中的名称创建身份验证方式
+func NewProvider(name string, rosterPath string) (IdentityProvider, error) {
+	switch name {
+	case "campus":
+		return Campus{}, nil
+	case "roster":
+		return LoadRoster(rosterPath)
+	case "local":
+		return Local{}, nil
+	}
+	return nil, fmt.Errorf("未知的身份验证方式: %s", name)
+}
+
+// Verify 使用该类人员对应的方式验证身份，统一验证关闭时改用备用方式
+func Verify(cred Credential) (*Identity, error) {
+	provider, ok := providers[cred.Type]
+	if !ok {
+		return nil, ErrNoProvider
+	}
+	identity, err := provider.Verify(cred)
+	if errors.Is(err, ErrClosed) && fallback != nil {
+		return fallback.Verify(cred)
+	}
+	return identity, err
+}
@@ -0,0 +1,236 @@
+package identityService
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zjutjh/WeJH-SDK/oauth"
+	"github.com/zjutjh/WeJH-SDK/oauth/oauthException"
+)
+
+func TestCampusVerify(t *testing.T) {
+	tests := []struct {
+		name     string
+		cred     Credential
+		info     oauth.UserInfo
+		err      error
+		noCookie bool
+		want     *Identity
+		wantErr  error
+	}{
+		{
+			name: "student",
+			cred: Credential{Account: "202101010101", Password: "pass"},
+			info: oauth.UserInfo{Name: "张三", Gender: "male", College: "计算机学院", UserTypeDesc: "本科生"},
+			want: &Identity{Name: "张三", Gender: 1, College: "计算机学院", Type: TypeStudent},
+		},
+		{
+			name: "teacher",
+			cred: Credential{Account: "100001", Password: "pass"},
+			info: oauth.UserInfo{Name: "李四", Gender: "female", College: "理学院", UserTypeDesc: "教师职工"},
+			want: &Identity{Name: "李四", Gender: 2, College: "理学院", Type: TypeTeacher},
+		},
+		{
+			name:    "empty account",
+			cred:    Credential{Account: " ", Password: "pass"},
+			wantErr: ErrWrongCredential,
+		},
+		{
+			name:    "empty password",
+			cred:    Credential{Account: "202101010101"},
+			wantErr: ErrWrongCredential,
+		},
+		{
+			name:    "wrong password",
+			cred:    Credential{Account: "202101010101", Password: "wrong"},
+			err:     oauthException.WrongPassword,
+			wantErr: ErrWrongCredential,
+		},
+		{
+			name:    "closed",
+			cred:    Credential{Account: "202101010101", Password: "pass"},
+			err:     oauthException.ClosedError,
+			wantErr: ErrClosed,
+		},
+		{
+			name:    "not activated",
+			cred:    Credential{Account: "202101010101", Password: "pass"},
+			err:     oauthException.NotActivatedError,
+			wantErr: ErrNotActivated,
+		},
+		{
+			name:     "no cookie",
+			cred:     Credential{Account: "202101010101", Password: "pass"},
+			noCookie: true,
+			wantErr:  ErrVerifyFailed,
+		},
+	}
+	defer func(original func(string, string) ([]*http.Cookie, oauth.UserInfo, error)) { getUserInfo = original }(getUserInfo)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			getUserInfo = func(string, string) ([]*http.Cookie, oauth.UserInfo, error) {
+				called = true
+				if tt.err != nil || tt.noCookie {
+					return nil, tt.info, tt.err
+				}
+				return []*http.Cookie{{Name: "session"}}, tt.info, nil
+			}
+			got, err := Campus{}.Verify(tt.cred)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.cred.Password == "" && called {
+				t.Fatal("Verify() requested campus auth with an empty password")
+			}
+			if tt.want != nil && *got != *tt.want {
+				t.Fatalf("Verify() = %+v, want %+v", *got, *tt.want)
+			}
+		})
+	}
+}
+
+func TestRosterVerify(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "roster.csv")
+	content := "学号,姓名,性别,学院,类型,身份证号\n" +
+		"202101010101,张三,男,计算机学院,1,440304199001010011\n" +
+		"100001,李四,女,理学院,2,11010519491231002x\n" +
+		"900001,王五,男,校友会,3,330106199805120036\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	roster, err := LoadRoster(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		cred    Credential
+		want    *Identity
+		wantErr error
+	}{
+		{
+			name: "student",
+			cred: Credential{Account: "202101010101", Identity: "440304199001010011"},
+			want: &Identity{Name: "张三", Gender: 1, College: "计算机学院", Type: TypeStudent},
+		},
+		{
+			name: "teacher lowercase x",
+			cred: Credential{Account: "100001", Identity: "11010519491231002x"},
+			want: &Identity{Name: "李四", Gender: 2, College: "理学院", Type: TypeTeacher},
+		},
+		{
+			name: "alumnus",
+			cred: Credential{Account: " 900001 ", Identity: "330106199805120036"},
+			want: &Identity{Name: "王五", Gender: 1, College: "校友会", Type: TypeAlumnus},
+		},
+		{
+			name:    "wrong identity",
+			cred:    Credential{Account: "202101010101", Identity: "11010519491231002X"},
+			wantErr: ErrNotInRoster,
+		},
+		{
+			name:    "unknown account",
+			cred:    Credential{Account: "202101010102", Identity: "440304199001010011"},
+			wantErr: ErrNotInRoster,
+		},
+		{
+			name:    "empty identity",
+			cred:    Credential{Account: "202101010101"},
+			wantErr: ErrNotInRoster,
+		},
+		{
+			name:    "empty account",
+			cred:    Credential{Identity: "440304199001010011"},
+			wantErr: ErrNotInRoster,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := roster.Verify(tt.cred)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.want != nil && *got != *tt.want {
+				t.Fatalf("Verify() = %+v, want %+v", *got, *tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadRosterRejectsBadRows(t *testing.T) {
+	tests := []struct {
+		name string
+		row  string
+	}{
+		{"missing columns", "202101010101,张三,男,计算机学院,1"},
+		{"bad type", "202101010101,张三,男,计算机学院,4,440304199001010011"},
+		{"empty account", ",张三,男,计算机学院,1,440304199001010011"},
+		{"empty identity", "202101010101,张三,男,计算机学院,1,"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "roster.csv")
+			content := "学号,姓名,性别,学院,类型,身份证号\n" + tt.row + "\n"
+			if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := LoadRoster(path); err == nil {
+				t.Fatal("LoadRoster() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestLocalVerify(t *testing.T) {
+	tests := []struct {
+		name string
+		cred Credential
+		want Identity
+	}{
+		{"student", Credential{Type: TypeStudent, Account: "1"}, Identity{Name: "测试1", Gender: 1, College: "测试学院", Type: TypeStudent}},
+		{"teacher", Credential{Type: TypeTeacher, Account: "2"}, Identity{Name: "测试2", Gender: 1, College: "测试学院", Type: TypeTeacher}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Local{}.Verify(tt.cred)
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if *got != tt.want {
+				t.Fatalf("Verify() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+// stubProvider 返回固定结果的身份验证方式
+type stubProvider struct {
+	identity *Identity
+	err      error
+}
+
+func (s stubProvider) Verify(Credential) (*Identity, error) {
+	return s.identity, s.err
+}
+
+func TestVerifyFallback(t *testing.T) {
+	defer func() {
+		providers = map[uint8]IdentityProvider{}
+		fallback = nil
+	}()
+	Register(TypeStudent, stubProvider{err: ErrClosed})
+	SetFallback(Local{})
+
+	got, err := Verify(Credential{Type: TypeStudent, Account: "1"})
+	if err != nil || got.Name != "测试1" {
+		t.Fatalf("Verify() = %+v, %v, want fallback identity", got, err)
+	}
+	if _, err := Verify(Credential{Type: TypeAlumnus}); !errors.Is(err, ErrNoProvider) {
+		t.Fatalf("Verify() error = %v, want %v", err, ErrNoProvider)
+	}
+}
@@ -0,0 +1,13 @@
+package identityService
+
+// Local 不做任何验证，只用于本地调试和测试
+type Local struct{}
+
+func (Local) Verify(cred Credential) (*Identity, error) {
+	return &Identity{
+		Name:    "测试" + cred.Account,
+		Gender:  1,
+		College: "测试学院",
+		Type:    cred.Type,
+	}, nil
+}
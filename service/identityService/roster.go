@@ -0,0 +1,76 @@
+package identityService
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Roster 根据导入的 CSV 名单验证学号和身份证号，不依赖统一验证
+// 名单第一行为表头，列依次为：学号,姓名,性别(男/女),学院,类型(1学生,2教职工,3校友),身份证号
+type Roster map[string]rosterEntry
+
+type rosterEntry struct {
+	Identity
+	IDCard string
+}
+
+// LoadRoster 读取 CSV 名单
+func LoadRoster(path string) (Roster, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	roster := make(Roster, len(records))
+	for i, record := range records {
+		if i == 0 {
+			continue
+		}
+		if len(record) < 6 {
+			return nil, fmt.Errorf("名单第 %d 行列数不足", i+1)
+		}
+		personType, err := strconv.ParseUint(strings.TrimSpace(record[4]), 10, 8)
+		if err != nil || !validType(uint8(personType)) {
+			return nil, fmt.Errorf("名单第 %d 行人员类型错误", i+1)
+		}
+		account, idCard := strings.TrimSpace(record[0]), strings.ToUpper(strings.TrimSpace(record[5]))
+		if account == "" || idCard == "" {
+			return nil, fmt.Errorf("名单第 %d 行学号或身份证号为空", i+1)
+		}
+		var gender int8 = 2
+		if strings.TrimSpace(record[2]) == "男" {
+			gender = 1
+		}
+		roster[account] = rosterEntry{
+			Identity: Identity{
+				Name:    strings.TrimSpace(record[1]),
+				Gender:  gender,
+				College: strings.TrimSpace(record[3]),
+				Type:    uint8(personType),
+			},
+			IDCard: idCard,
+		}
+	}
+	return roster, nil
+}
+
+func (r Roster) Verify(cred Credential) (*Identity, error) {
+	account, idCard := strings.TrimSpace(cred.Account), strings.ToUpper(strings.TrimSpace(cred.Identity))
+	if account == "" || idCard == "" {
+		return nil, ErrNotInRoster
+	}
+	entry, ok := r[account]
+	if !ok || entry.IDCard != idCard {
+		return nil, ErrNotInRoster
+	}
+	identity := entry.Identity
+	return &identity, nil
+}
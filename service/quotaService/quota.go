@@ -0,0 +1,275 @@
+package quotaService
+
+import (
+	"errors"
+	"strconv"
+	"walk-server/global"
+	"walk-server/model"
+	"walk-server/service/teamService"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrBelowUsed 名额总数少于已经提交的队伍数
+var ErrBelowUsed = errors.New("quota below used")
+
+// ErrInvalidTotal 名额总数不能为负数
+var ErrInvalidTotal = errors.New("invalid quota total")
+
+// 按变化量调整剩余名额，剩余名额不足以扣减时不做修改
+// Redis 中没有剩余名额时按新的总数减去 MySQL 中已提交的队伍数重新写入，总数少于已提交的队伍数时不做修改
+var adjust = redis.NewScript(`
+local dailyRouteKey = KEYS[1];
+local delta = tonumber(ARGV[1]);
+local total = tonumber(ARGV[2]);
+local used = tonumber(ARGV[3]);
+
+local num = redis.call("get", dailyRouteKey);
+if not num then
+	if total < used then
+		return {0, 0};
+	end
+	redis.call("set", dailyRouteKey, total - used);
+	return {1, total - used, 1};
+end
+
+local remaining = tonumber(num) + delta;
+if remaining < 0 then
+	return {0, tonumber(num)};
+end
+redis.call("incrby", dailyRouteKey, delta);
+return {1, remaining};
+`)
+
+// Status 某天某条路线的名额使用情况
+type Status struct {
+	Day       uint8 `json:"day"`
+	Route     uint8 `json:"route"`
+	Total     int   `json:"total"`
+	Remaining int   `json:"remaining"`
+	Used      int   `json:"used"`
+	Waitlist  int64 `json:"waitlist"` // 候补队伍数
+}
+
+// Init 将配置文件中的 teamUpperLimit 写入数据库中还没有的名额，Redis 中缺失的剩余名额按总数减去 MySQL 中已提交的队伍数恢复
+// 数据库中已有的名额以数据库为准，修改配置文件不会覆盖管理员调整过的名额
+func Init() error {
+	var quotas []model.Quota
+	for dayKey := range global.Config.GetStringMap("teamUpperLimit") {
+		day, err := strconv.Atoi(dayKey)
+		if err != nil {
+			continue
+		}
+		for routeKey := range global.Config.GetStringMap("teamUpperLimit." + dayKey) {
+			route, err := strconv.Atoi(routeKey)
+			if err != nil {
+				continue
+			}
+			quotas = append(quotas, model.Quota{
+				Day:   uint8(day),
+				Route: uint8(route),
+				Total: global.Config.GetInt("teamUpperLimit." + dayKey + "." + routeKey),
+			})
+		}
+	}
+	if len(quotas) > 0 {
+		if err := global.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&quotas).Error; err != nil {
+			return err
+		}
+	}
+
+	quotas, err := model.GetQuotas()
+	if err != nil {
+		return err
+	}
+	used, err := CountSubmitted()
+	if err != nil {
+		return err
+	}
+	for _, quota := range quotas {
+		key := teamService.DailyRouteKey(quota.Day, quota.Route)
+		remaining := max(quota.Total-used[[2]uint8{quota.Day, quota.Route}], 0)
+		if err := global.Rdb.SetNX(global.Rctx, key, remaining, 0).Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// countSubmittedOn 统计 MySQL 中某天某条路线占用名额的已提交队伍数
+func countSubmittedOn(day uint8, route uint8) (int, error) {
+	var count int64
+	err := global.DB.Model(&model.Team{}).
+		Where("submit = ? AND submit_day = ? AND route = ?", true, day, route).Count(&count).Error
+	return int(count), err
+}
+
+// CountSubmitted 统计 MySQL 中每天各路线占用名额的已提交队伍数
+func CountSubmitted() (map[[2]uint8]int, error) {
+	var rows []struct {
+		SubmitDay uint8
+		Route     uint8
+		Count     int
+	}
+	err := global.DB.Model(&model.Team{}).Select("submit_day, route, COUNT(*) AS count").
+		Where("submit = ? AND submit_day IS NOT NULL", true).Group("submit_day, route").Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	used := make(map[[2]uint8]int, len(rows))
+	for _, row := range rows {
+		used[[2]uint8{row.SubmitDay, row.Route}] = row.Count
+	}
+	return used, nil
+}
+
+// Recount 按 MySQL 中已提交的队伍数重新计算全部剩余名额，返回剩余名额增加了的名额，调用方需要持有 teamService.LockSubmissions
+func Recount() ([]model.Quota, error) {
+	quotas, err := model.GetQuotas()
+	if err != nil {
+		return nil, err
+	}
+	used, err := CountSubmitted()
+	if err != nil {
+		return nil, err
+	}
+	var increased []model.Quota
+	for _, quota := range quotas {
+		key := teamService.DailyRouteKey(quota.Day, quota.Route)
+		remaining := max(quota.Total-used[[2]uint8{quota.Day, quota.Route}], 0)
+		old, err := global.Rdb.GetSet(global.Rctx, key, remaining).Int()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return nil, err
+		}
+		if remaining > old {
+			increased = append(increased, quota)
+		}
+	}
+	return increased, nil
+}
+
+// List 获取全部名额的总数、剩余和已使用数量
+func List() ([]Status, error) {
+	quotas, err := model.GetQuotas()
+	if err != nil {
+		return nil, err
+	}
+
+	pipe := global.Rdb.Pipeline()
+	remainings := make([]*redis.StringCmd, len(quotas))
+	waitlists := make([]*redis.IntCmd, len(quotas))
+	for i, quota := range quotas {
+		key := teamService.DailyRouteKey(quota.Day, quota.Route)
+		remainings[i] = pipe.Get(global.Rctx, key)
+		waitlists[i] = pipe.LLen(global.Rctx, teamService.WaitlistKey(key))
+	}
+	if _, err := pipe.Exec(global.Rctx); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+
+	// Redis 中没有剩余名额时按 MySQL 中已提交的队伍数计算
+	used, err := CountSubmitted()
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]Status, 0, len(quotas))
+	for i, quota := range quotas {
+		remaining, err := remainings[i].Int()
+		if errors.Is(err, redis.Nil) {
+			remaining = max(quota.Total-used[[2]uint8{quota.Day, quota.Route}], 0)
+		} else if err != nil {
+			return nil, err
+		}
+		list = append(list, Status{
+			Day:       quota.Day,
+			Route:     quota.Route,
+			Total:     quota.Total,
+			Remaining: remaining,
+			Used:      quota.Total - remaining,
+			Waitlist:  waitlists[i].Val(),
+		})
+	}
+	return list, nil
+}
+
+// SetTotal 修改名额总数
+func SetTotal(day uint8, route uint8, total int) (*Status, error) {
+	return update(day, route, func(int) int { return total })
+}
+
+// Adjust 在当前名额总数上增加或减少
+func Adjust(day uint8, route uint8, delta int) (*Status, error) {
+	return update(day, route, func(old int) int { return old + delta })
+}
+
+// update 锁住数据库中的名额记录，Redis 中的剩余名额按总数的变化量原子调整，数据库写入失败时撤回 Redis 的调整
+// 调整期间持有提交的读锁，避免对账修复按旧的总数重新计算剩余名额
+// 名额增加时按顺序提交候补的队伍
+func update(day uint8, route uint8, fn func(old int) int) (*Status, error) {
+	key := teamService.DailyRouteKey(day, route)
+	var status Status
+	unlock := teamService.ShareSubmissions()
+	err := global.DB.Transaction(func(tx *gorm.DB) error {
+		quota := model.Quota{Day: day, Route: route}
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("day = ? AND route = ?", day, route).Take(&quota).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		total := fn(quota.Total)
+		if total < 0 {
+			return ErrInvalidTotal
+		}
+		delta := total - quota.Total
+		used, err := countSubmittedOn(day, route)
+		if err != nil {
+			return err
+		}
+		values, err := adjust.Run(global.Rctx, global.Rdb, []string{key}, delta, total, used).Int64Slice()
+		if err != nil {
+			return err
+		}
+		if values[0] == 0 {
+			return ErrBelowUsed
+		}
+
+		quota.Total = total
+		if err := tx.Save(&quota).Error; err != nil {
+			if len(values) > 2 {
+				// 剩余名额是这次重新写入的，删除后下次按 MySQL 重新计算
+				global.Rdb.Del(global.Rctx, key)
+			} else {
+				global.Rdb.DecrBy(global.Rctx, key, int64(delta))
+			}
+			return err
+		}
+		status = Status{
+			Day:       day,
+			Route:     route,
+			Total:     total,
+			Remaining: int(values[1]),
+			Used:      total - int(values[1]),
+		}
+		return nil
+	})
+	unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := teamService.PromoteWaitlist(day, route); err != nil {
+		return nil, err
+	}
+	remaining, err := global.Rdb.Get(global.Rctx, key).Int()
+	if err != nil {
+		return nil, err
+	}
+	status.Remaining = remaining
+	status.Used = status.Total - remaining
+	status.Waitlist, _ = global.Rdb.LLen(global.Rctx, teamService.WaitlistKey(key)).Result()
+	return &status, nil
+}
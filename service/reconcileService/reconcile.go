@@ -0,0 +1,259 @@
+// Package reconcileService 定期比对 Redis 中的提交状态、剩余名额和 MySQL 中的队伍，按 MySQL 修复 Redis 中的提交集合并生成报告
+package reconcileService
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+	"walk-server/global"
+	"walk-server/model"
+	"walk-server/service/quotaService"
+	"walk-server/service/teamService"
+)
+
+// SubmitDrift MySQL 中的 Team.Submit 与 Redis 提交集合不一致的队伍，以 MySQL 为准
+type SubmitDrift struct {
+	TeamID uint  `json:"team_id"`
+	Route  uint8 `json:"route"`
+	Redis  bool  `json:"redis"`
+	MySQL  bool  `json:"mysql"`
+}
+
+// QuotaDrift 某天某条路线已使用的名额与 MySQL 中当天提交的队伍数不一致，修复时按 MySQL 重新计算剩余名额
+// 管理员提交和迁移的队伍没有提交日期，不占用名额
+type QuotaDrift struct {
+	Day       uint8 `json:"day"`
+	Route     uint8 `json:"route"`
+	Used      int   `json:"used"`      // 名额总数减剩余名额
+	Submitted int   `json:"submitted"` // MySQL 中当天提交的队伍数
+}
+
+// CounterDrift 剩余名额为负数或者超过名额总数
+type CounterDrift struct {
+	Day       uint8 `json:"day"`
+	Route     uint8 `json:"route"`
+	Total     int   `json:"total"`
+	Remaining int   `json:"remaining"`
+}
+
+// Report 一次比对的结果
+type Report struct {
+	CheckedAt   time.Time      `json:"checked_at"`
+	Repaired    bool           `json:"repaired"`     // 是否已经修复 Redis 中的提交集合和剩余名额
+	Submit      []SubmitDrift  `json:"submit"`       // 提交状态不一致的队伍
+	StaleTeams  []string       `json:"stale_teams"`  // Redis 提交集合中已经不存在的队伍
+	StaleWaits  []string       `json:"stale_waits"`  // 已提交或已不存在但仍在候补中的队伍
+	Quotas      []QuotaDrift   `json:"quotas"`       // 已使用名额与提交队伍数不一致的名额
+	Counters    []CounterDrift `json:"counters"`     // 超出范围的剩余名额
+	Consistent  bool           `json:"consistent"`   // 没有发现任何不一致
+	ElapsedTime string         `json:"elapsed_time"` // 比对耗时
+}
+
+var (
+	mu     sync.Mutex // 同一时间只运行一次比对
+	last   *Report
+	lastMu sync.RWMutex
+)
+
+// Last 获取最近一次比对的报告，还没有运行过时返回 nil
+func Last() *Report {
+	lastMu.RLock()
+	defer lastMu.RUnlock()
+	return last
+}
+
+// Run 比对 Redis 和 MySQL，repair 为 true 时按 MySQL 修复 Redis 中的提交集合和剩余名额，并清理失效的提交和候补记录
+func Run(repair bool) (*Report, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	start := time.Now()
+	report := &Report{
+		CheckedAt:  start,
+		Repaired:   repair,
+		Submit:     make([]SubmitDrift, 0),
+		StaleTeams: make([]string, 0),
+		StaleWaits: make([]string, 0),
+		Quotas:     make([]QuotaDrift, 0),
+		Counters:   make([]CounterDrift, 0),
+	}
+
+	members, err := global.Rdb.SMembers(global.Rctx, "teams").Result()
+	if err != nil {
+		return nil, err
+	}
+	submitted := make(map[string]bool, len(members))
+	for _, id := range members {
+		submitted[id] = true
+	}
+
+	var teams []model.Team
+	if err := global.DB.Select("id", "route", "submit").Find(&teams).Error; err != nil {
+		return nil, err
+	}
+	exists := make(map[string]bool, len(teams))
+	var toAdd, toRemove []string
+	for _, team := range teams {
+		id := strconv.Itoa(int(team.ID))
+		exists[id] = true
+		inRedis := submitted[id]
+		if inRedis == team.Submit {
+			continue
+		}
+		report.Submit = append(report.Submit, SubmitDrift{
+			TeamID: team.ID,
+			Route:  team.Route,
+			Redis:  inRedis,
+			MySQL:  team.Submit,
+		})
+		if team.Submit {
+			toAdd = append(toAdd, id)
+		} else {
+			toRemove = append(toRemove, id)
+		}
+	}
+	for _, id := range members {
+		if !exists[id] {
+			report.StaleTeams = append(report.StaleTeams, id)
+		}
+	}
+	sort.Strings(report.StaleTeams)
+
+	waiting, err := global.Rdb.HKeys(global.Rctx, "waitlist:teams").Result()
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range waiting {
+		if submitted[id] || !exists[id] {
+			report.StaleWaits = append(report.StaleWaits, id)
+		}
+	}
+	sort.Strings(report.StaleWaits)
+
+	quotas, err := quotaService.List()
+	if err != nil {
+		return nil, err
+	}
+	used, err := quotaService.CountSubmitted()
+	if err != nil {
+		return nil, err
+	}
+	for _, quota := range quotas {
+		count := used[[2]uint8{quota.Day, quota.Route}]
+		if quota.Used != count {
+			report.Quotas = append(report.Quotas, QuotaDrift{
+				Day:       quota.Day,
+				Route:     quota.Route,
+				Used:      quota.Used,
+				Submitted: count,
+			})
+		}
+		if quota.Remaining < 0 || quota.Remaining > quota.Total {
+			report.Counters = append(report.Counters, CounterDrift{
+				Day:       quota.Day,
+				Route:     quota.Route,
+				Total:     quota.Total,
+				Remaining: quota.Remaining,
+			})
+		}
+	}
+
+	report.Consistent = len(report.Submit) == 0 && len(report.StaleTeams) == 0 && len(report.StaleWaits) == 0 &&
+		len(report.Quotas) == 0 && len(report.Counters) == 0
+
+	if repair {
+		if err := repairDrift(report, toAdd, append(toRemove, report.StaleTeams...)); err != nil {
+			return nil, err
+		}
+	}
+
+	report.ElapsedTime = time.Since(start).String()
+	lastMu.Lock()
+	last = report
+	lastMu.Unlock()
+	return report, nil
+}
+
+// repairDrift 按 MySQL 修复 Redis 中的提交集合，重新计算剩余名额，并移除失效的候补记录
+// 修复时暂停提交和撤销，并重新读取每支不一致的队伍，比对期间已经恢复一致的队伍不做修改
+// 移除 Redis 中多出的提交后空出的名额让给候补的队伍
+func repairDrift(report *Report, toAdd []string, toRemove []string) error {
+	increased, err := repairLocked(report, toAdd, toRemove)
+	if err != nil {
+		return err
+	}
+	for _, quota := range increased {
+		if err := teamService.PromoteWaitlist(quota.Day, quota.Route); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// repairLocked 在暂停提交和撤销期间修复，返回剩余名额增加了的名额
+func repairLocked(report *Report, toAdd []string, toRemove []string) ([]model.Quota, error) {
+	unlock := teamService.LockSubmissions()
+	defer unlock()
+
+	toAdd, err := stillDrifted(toAdd, true)
+	if err != nil {
+		return nil, err
+	}
+	toRemove, err = stillDrifted(toRemove, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(toAdd) > 0 {
+		if err := global.Rdb.SAdd(global.Rctx, "teams", toAny(toAdd)...).Err(); err != nil {
+			return nil, err
+		}
+	}
+	if len(toRemove) > 0 {
+		if err := global.Rdb.SRem(global.Rctx, "teams", toAny(toRemove)...).Err(); err != nil {
+			return nil, err
+		}
+	}
+	for _, id := range report.StaleWaits {
+		teamID, _ := strconv.Atoi(id)
+		if err := teamService.LeaveWaitlist(uint(teamID)); err != nil {
+			return nil, err
+		}
+	}
+	return quotaService.Recount()
+}
+
+// stillDrifted 重新读取队伍，返回 MySQL 中的提交状态仍为 submitted、而 Redis 中不是的队伍
+func stillDrifted(ids []string, submitted bool) ([]string, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	var teams []model.Team
+	if err := global.DB.Select("id", "submit").Where("id IN ?", ids).Find(&teams).Error; err != nil {
+		return nil, err
+	}
+	inMySQL := make(map[string]bool, len(teams))
+	for _, team := range teams {
+		inMySQL[strconv.Itoa(int(team.ID))] = team.Submit
+	}
+	inRedis, err := global.Rdb.SMIsMember(global.Rctx, "teams", toAny(ids)...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	drifted := make([]string, 0, len(ids))
+	for i, id := range ids {
+		if inMySQL[id] == submitted && inRedis[i] != submitted {
+			drifted = append(drifted, id)
+		}
+	}
+	return drifted, nil
+}
+
+func toAny(ids []string) []any {
+	values := make([]any, 0, len(ids))
+	for _, id := range ids {
+		values = append(values, id)
+	}
+	return values
+}
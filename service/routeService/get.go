@@ -0,0 +1,71 @@
+package routeService
+
+import (
+	"walk-server/constant"
+	"walk-server/global"
+	"walk-server/model"
+)
+
+func GetRouteByID(id uint8) (*model.Route, error) {
+	route := model.Route{}
+	result := global.DB.Where("id = ?", id).First(&route)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &route, nil
+}
+
+func GetCheckpoint(routeID uint8, point int8) (*model.Checkpoint, error) {
+	checkpoint := model.Checkpoint{}
+	result := global.DB.Where("route_id = ? AND point = ?", routeID, point).First(&checkpoint)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &checkpoint, nil
+}
+
+func GetSiteByID(id uint) (*model.Site, error) {
+	site := model.Site{}
+	result := global.DB.Where("id = ?", id).First(&site)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &site, nil
+}
+
+// Load 从数据库读取路线和点位，刷新 constant 中的路线数据
+func Load() error {
+	routes, err := model.GetRoutes()
+	if err != nil {
+		return err
+	}
+	checkpoints, err := model.GetCheckpoints()
+	if err != nil {
+		return err
+	}
+
+	points := make(map[uint8]map[int8]string)
+	sites := make(map[uint8]map[int8]uint)
+	for _, checkpoint := range checkpoints {
+		if points[checkpoint.RouteID] == nil {
+			points[checkpoint.RouteID] = make(map[int8]string)
+			sites[checkpoint.RouteID] = make(map[int8]uint)
+		}
+		points[checkpoint.RouteID][checkpoint.Point] = checkpoint.Name
+		sites[checkpoint.RouteID][checkpoint.Point] = checkpoint.SiteID
+	}
+
+	list := make([]constant.RouteInfo, 0, len(routes))
+	for _, route := range routes {
+		list = append(list, constant.RouteInfo{
+			ID:     route.ID,
+			Code:   route.Code,
+			Name:   route.Name,
+			Campus: route.Campus,
+			Points: points[route.ID],
+			Sites:  sites[route.ID],
+		})
+	}
+	constant.SetRoutes(list)
+	return nil
+}
@@ -0,0 +1,82 @@
+package routeService
+
+import (
+	"errors"
+	"walk-server/constant"
+)
+
+var (
+	ErrUnknownPoint = errors.New("管理员点位不存在")
+	ErrOtherRoute   = errors.New("该队伍为其他路线")
+	ErrPassBy       = errors.New("该点位不在队伍路线上")
+	ErrBackward     = errors.New("队伍已经过该点位，不能回退扫码")
+)
+
+// IsConnected 判断两条路线是否相同或者有共用的地点
+func IsConnected(a uint8, b uint8) bool {
+	if a == b {
+		return true
+	}
+	routeA, okA := constant.GetRoute(a)
+	routeB, okB := constant.GetRoute(b)
+	if !okA || !okB {
+		return false
+	}
+
+	for _, siteA := range routeA.Sites {
+		if siteA == 0 {
+			continue
+		}
+		for _, siteB := range routeB.Sites {
+			if siteA == siteB {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// LocatePoint 根据管理员所在的路线点位，找到队伍所在路线上对应的点位编号。
+// 管理员所在地点不在队伍路线上时，如果两条路线有共用地点则返回 ErrPassBy（队伍只是经过），否则返回 ErrOtherRoute
+func LocatePoint(adminRoute uint8, adminPoint int8, teamRoute uint8) (int8, error) {
+	admin, ok := constant.GetRoute(adminRoute)
+	if !ok {
+		return 0, ErrUnknownPoint
+	}
+	if _, ok := admin.Points[adminPoint]; !ok {
+		return 0, ErrUnknownPoint
+	}
+	if adminRoute == teamRoute {
+		return adminPoint, nil
+	}
+
+	team, ok := constant.GetRoute(teamRoute)
+	if !ok {
+		return 0, ErrOtherRoute
+	}
+
+	site := admin.Sites[adminPoint]
+	if site != 0 {
+		for point, teamSite := range team.Sites {
+			if teamSite == site {
+				return point, nil
+			}
+		}
+	}
+
+	if IsConnected(adminRoute, teamRoute) {
+		return 0, ErrPassBy
+	}
+	return 0, ErrOtherRoute
+}
+
+// CheckProgress 校验队伍从 current 点位前进到 next 点位是否符合路线顺序，返回中间跳过的点位数量
+func CheckProgress(current int8, next int8) (int8, error) {
+	if next < current {
+		return 0, ErrBackward
+	}
+	if current < 0 || next <= current+1 {
+		return 0, nil
+	}
+	return next - current - 1, nil
+}
@@ -0,0 +1,68 @@
+package routeService
+
+import (
+	"walk-server/global"
+	"walk-server/model"
+
+	"gorm.io/gorm"
+)
+
+func SaveRoute(route model.Route) error {
+	if err := global.DB.Save(&route).Error; err != nil {
+		return err
+	}
+	return Load()
+}
+
+// DeleteRoute 删除路线以及路线下的全部点位
+func DeleteRoute(id uint8) error {
+	err := global.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("route_id = ?", id).Delete(&model.Checkpoint{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.Route{}, id).Error
+	})
+	if err != nil {
+		return err
+	}
+	return Load()
+}
+
+// SaveCheckpoint 新增或修改点位，同一路线同一编号只保留一个点位
+func SaveCheckpoint(checkpoint model.Checkpoint) error {
+	if old, err := GetCheckpoint(checkpoint.RouteID, checkpoint.Point); err == nil {
+		checkpoint.ID = old.ID
+	}
+	if err := global.DB.Save(&checkpoint).Error; err != nil {
+		return err
+	}
+	return Load()
+}
+
+func DeleteCheckpoint(routeID uint8, point int8) error {
+	if err := global.DB.Where("route_id = ? AND point = ?", routeID, point).Delete(&model.Checkpoint{}).Error; err != nil {
+		return err
+	}
+	return Load()
+}
+
+func SaveSite(site model.Site) error {
+	if err := global.DB.Save(&site).Error; err != nil {
+		return err
+	}
+	return Load()
+}
+
+// DeleteSite 删除地点，引用该地点的点位不再与其他路线共用
+func DeleteSite(id uint) error {
+	err := global.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.Checkpoint{}).Where("site_id = ?", id).Update("site_id", 0).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.Site{}, id).Error
+	})
+	if err != nil {
+		return err
+	}
+	return Load()
+}
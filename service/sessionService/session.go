@@ -0,0 +1,279 @@
+// Package sessionService 管理登录会话：签发短期 access token 和可轮换的 refresh token，支持查看和撤销会话
+// 会话保存在 Redis 中，撤销的会话在 access token 有效期内记录在撤销列表里，由 IsRegistered 和 CheckAdmin 检查
+package sessionService
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"walk-server/global"
+	"walk-server/service/adminService"
+	"walk-server/utility"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	ErrSessionExpired = errors.New("session expired")
+	ErrRefreshReused  = errors.New("refresh token reused")
+)
+
+// Session 一次登录产生的会话
+type Session struct {
+	ID          string    `json:"id"`
+	Kind        string    `json:"kind"`
+	IP          string    `json:"ip"`
+	UserAgent   string    `json:"user_agent"`
+	CreatedAt   time.Time `json:"created_at"`
+	RefreshedAt time.Time `json:"refreshed_at"`
+	Current     bool      `json:"current"` // 是否为发起请求的会话
+}
+
+// 校验 refresh token 并原子地替换为新的 refresh token，同时保留上一个 refresh token 的哈希
+// 返回 0 表示会话不存在或密钥不匹配，-1 表示出示的是上一个已经使用过的 refresh token，1 表示替换成功
+// 会话 ID 不是秘密，只有出示上一个 refresh token 才视为泄漏，随意伪造的密钥不能撤销别人的会话
+var rotate = redis.NewScript(`
+local hash = redis.call("hget", KEYS[1], "refresh");
+if not hash then
+	return 0;
+end
+if hash == ARGV[1] then
+	redis.call("hset", KEYS[1], "refresh", ARGV[2], "previous", ARGV[1], "refreshed", ARGV[3]);
+	redis.call("expire", KEYS[1], ARGV[4]);
+	return 1;
+end
+if redis.call("hget", KEYS[1], "previous") == ARGV[1] then
+	return -1;
+end
+return 0;
+`)
+
+func sessionKey(id string) string {
+	return "session:" + id
+}
+
+func subjectKey(kind string, subject string) string {
+	return "sessions:" + kind + ":" + subject
+}
+
+func revokedKey(id string) string {
+	return "revoked:" + id
+}
+
+// RefreshTokenLifetime 会话和 refresh token 的有效期，读取配置 jwt.refresh（小时），默认 7 天，每次刷新后重新计算
+func RefreshTokenLifetime() time.Duration {
+	hours := global.Config.GetInt("jwt.refresh")
+	if hours <= 0 {
+		hours = 24 * 7
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+// subjectOf 会话所属的参与者 OpenID 或管理员ID
+func subjectOf(claims *utility.JwtData) string {
+	if claims.Kind == utility.KindAdmin {
+		return strconv.Itoa(int(claims.AdminID))
+	}
+	return claims.OpenID
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// newRefreshToken 生成 refresh token，格式为 会话ID.随机密钥，Redis 中只保存密钥的哈希
+func newRefreshToken(id string) (string, string, error) {
+	secret, err := utility.RandomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+	return id + "." + secret, hashSecret(secret), nil
+}
+
+// Create 创建会话，返回 access token 和 refresh token
+func Create(c *gin.Context, claims *utility.JwtData) (string, string, error) {
+	id, err := utility.RandomToken(16)
+	if err != nil {
+		return "", "", err
+	}
+	refreshToken, hash, err := newRefreshToken(id)
+	if err != nil {
+		return "", "", err
+	}
+
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	lifetime := RefreshTokenLifetime()
+	subject := subjectKey(claims.Kind, subjectOf(claims))
+	pipe := global.Rdb.TxPipeline()
+	pipe.HSet(global.Rctx, sessionKey(id), map[string]any{
+		"kind":      claims.Kind,
+		"subject":   subjectOf(claims),
+		"ip":        c.ClientIP(),
+		"ua":        c.Request.UserAgent(),
+		"created":   now,
+		"refreshed": now,
+		"refresh":   hash,
+	})
+	pipe.Expire(global.Rctx, sessionKey(id), lifetime)
+	pipe.SAdd(global.Rctx, subject, id)
+	pipe.Expire(global.Rctx, subject, lifetime)
+	if _, err := pipe.Exec(global.Rctx); err != nil {
+		return "", "", err
+	}
+
+	claims.Session = id
+	accessToken, err := utility.GenerateStandardJwt(claims)
+	if err != nil {
+		return "", "", err
+	}
+	return accessToken, refreshToken, nil
+}
+
+// Refresh 使用 refresh token 换取新的 access token，同时轮换 refresh token
+// 上一个已经被使用过的 refresh token 再次出现说明可能已经泄漏，直接撤销整个会话，其他不匹配的密钥只当作会话失效
+func Refresh(refreshToken string, kind string) (string, string, error) {
+	id, secret, ok := strings.Cut(refreshToken, ".")
+	if !ok || id == "" || secret == "" {
+		return "", "", ErrSessionExpired
+	}
+	values, err := global.Rdb.HMGet(global.Rctx, sessionKey(id), "kind", "subject").Result()
+	if err != nil {
+		return "", "", err
+	}
+	sessionKind, _ := values[0].(string)
+	subject, _ := values[1].(string)
+	if sessionKind != kind || subject == "" {
+		return "", "", ErrSessionExpired
+	}
+
+	// 管理员的角色、路线和点位以数据库为准
+	claims := utility.NewUserClaims(subject)
+	if kind == utility.KindAdmin {
+		adminID, _ := strconv.Atoi(subject)
+		admin, err := adminService.GetAdminByID(uint(adminID))
+		if err != nil {
+			return "", "", ErrSessionExpired
+		}
+		claims = utility.NewAdminClaims(admin)
+	}
+
+	newToken, hash, err := newRefreshToken(id)
+	if err != nil {
+		return "", "", err
+	}
+	lifetime := int(RefreshTokenLifetime().Seconds())
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	result, err := rotate.Run(global.Rctx, global.Rdb, []string{sessionKey(id)}, hashSecret(secret), hash, now, lifetime).Int()
+	if err != nil {
+		return "", "", err
+	}
+	switch result {
+	case 0:
+		return "", "", ErrSessionExpired
+	case -1:
+		_ = Revoke(id)
+		return "", "", ErrRefreshReused
+	}
+	global.Rdb.Expire(global.Rctx, subjectKey(kind, subject), RefreshTokenLifetime())
+
+	claims.Session = id
+	accessToken, err := utility.GenerateStandardJwt(claims)
+	if err != nil {
+		return "", "", err
+	}
+	return accessToken, newToken, nil
+}
+
+// IsRevoked 判断会话是否已经被撤销，Redis 出错时无法确认，按已撤销处理
+func IsRevoked(id string) bool {
+	n, err := global.Rdb.Exists(global.Rctx, revokedKey(id)).Result()
+	if err != nil {
+		log.Println("会话撤销状态查询失败: ", err)
+		return true
+	}
+	return n > 0
+}
+
+// List 获取参与者或管理员的全部有效会话，按创建时间倒序
+func List(kind string, subject string) ([]Session, error) {
+	ids, err := global.Rdb.SMembers(global.Rctx, subjectKey(kind, subject)).Result()
+	if err != nil {
+		return nil, err
+	}
+	sessions := make([]Session, 0, len(ids))
+	for _, id := range ids {
+		values, err := global.Rdb.HGetAll(global.Rctx, sessionKey(id)).Result()
+		if err != nil {
+			return nil, err
+		}
+		if len(values) == 0 {
+			// 会话已过期
+			global.Rdb.SRem(global.Rctx, subjectKey(kind, subject), id)
+			continue
+		}
+		created, _ := strconv.ParseInt(values["created"], 10, 64)
+		refreshed, _ := strconv.ParseInt(values["refreshed"], 10, 64)
+		sessions = append(sessions, Session{
+			ID:          id,
+			Kind:        values["kind"],
+			IP:          values["ip"],
+			UserAgent:   values["ua"],
+			CreatedAt:   time.Unix(created, 0),
+			RefreshedAt: time.Unix(refreshed, 0),
+		})
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].CreatedAt.After(sessions[j].CreatedAt) })
+	return sessions, nil
+}
+
+// Owns 判断会话是否属于指定的参与者或管理员
+func Owns(kind string, subject string, id string) bool {
+	ok, _ := global.Rdb.SIsMember(global.Rctx, subjectKey(kind, subject), id).Result()
+	return ok
+}
+
+// Revoke 撤销会话，refresh token 立即失效，已签发的 access token 在有效期内记录在撤销列表中
+func Revoke(id string) error {
+	values, err := global.Rdb.HMGet(global.Rctx, sessionKey(id), "kind", "subject").Result()
+	if err != nil {
+		return err
+	}
+	pipe := global.Rdb.TxPipeline()
+	pipe.Del(global.Rctx, sessionKey(id))
+	if kind, ok := values[0].(string); ok {
+		subject, _ := values[1].(string)
+		pipe.SRem(global.Rctx, subjectKey(kind, subject), id)
+	}
+	pipe.Set(global.Rctx, revokedKey(id), 1, utility.AccessTokenLifetime())
+	_, err = pipe.Exec(global.Rctx)
+	return err
+}
+
+// RevokeAll 撤销参与者或管理员除 keep 以外的全部会话，keep 为空时全部撤销
+func RevokeAll(kind string, subject string, keep string) error {
+	ids, err := global.Rdb.SMembers(global.Rctx, subjectKey(kind, subject)).Result()
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if id == keep {
+			continue
+		}
+		if err := Revoke(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AdminSubject 管理员会话的所属标识
+func AdminSubject(adminID uint) string {
+	return strconv.Itoa(int(adminID))
+}
@@ -0,0 +1,55 @@
+package sessionService
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+	"walk-server/global"
+	"walk-server/utility"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrTicketInvalid 登录票据不存在、已过期或已被使用
+var ErrTicketInvalid = errors.New("login ticket invalid")
+
+// 微信回调后只把一次性的登录票据交给前端，前端再用票据换取登录凭证，票据在 Redis 中只能兑换一次
+const ticketLifetime = 2 * time.Minute
+
+func ticketKey(id string) string {
+	return "login:ticket:" + id
+}
+
+func signTicket(id string) string {
+	mac := hmac.New(sha256.New, []byte(global.Config.GetString("server.JWTSecret")))
+	mac.Write([]byte("ticket:" + id))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// CreateTicket 为通过微信验证的参与者生成登录票据，格式为 票据ID.签名
+func CreateTicket(openID string) (string, error) {
+	id, err := utility.RandomToken(16)
+	if err != nil {
+		return "", err
+	}
+	if err := global.Rdb.Set(global.Rctx, ticketKey(id), openID, ticketLifetime).Err(); err != nil {
+		return "", err
+	}
+	return id + "." + signTicket(id), nil
+}
+
+// ExchangeTicket 兑换登录票据，返回参与者加密后的 OpenID，票据兑换后立即删除
+func ExchangeTicket(ticket string) (string, error) {
+	id, sign, ok := strings.Cut(ticket, ".")
+	if !ok || !hmac.Equal([]byte(sign), []byte(signTicket(id))) {
+		return "", ErrTicketInvalid
+	}
+	openID, err := global.Rdb.GetDel(global.Rctx, ticketKey(id)).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", ErrTicketInvalid
+	}
+	return openID, err
+}
@@ -3,6 +3,8 @@ package teamService
 import (
 	"walk-server/global"
 	"walk-server/model"
+
+	"gorm.io/gorm"
 )
 
 func GetTeamByID(id uint) (*model.Team, error) {
@@ -40,3 +42,12 @@ func GetTeamByCaptain(captainID string) (*model.Team, error) {
 
 	return &team, result.Error
 }
+
+// TxGetTeamByID 在事务中读取队伍
+func TxGetTeamByID(tx *gorm.DB, id uint) (*model.Team, error) {
+	team := model.Team{}
+	if err := tx.Where("id = ?", id).First(&team).Error; err != nil {
+		return nil, err
+	}
+	return &team, nil
+}
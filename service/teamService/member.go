@@ -0,0 +1,113 @@
+package teamService
+
+import (
+	"errors"
+	"walk-server/global"
+	"walk-server/model"
+	"walk-server/utility"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrTeamSubmitted 队伍已提交，不能退出或解散
+var ErrTeamSubmitted = errors.New("team submitted")
+
+// txLockTeam 在事务中锁定队伍，已提交的队伍不能退出或解散
+func txLockTeam(tx *gorm.DB, teamID int, team *model.Team) error {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", teamID).Take(team).Error; err != nil {
+		return err
+	}
+	if team.Submit {
+		return ErrTeamSubmitted
+	}
+	return nil
+}
+
+// Leave 队员离开队伍，并通知队伍中剩下的成员
+func Leave(person *model.Person) error {
+	var team model.Team
+	err := global.DB.Transaction(func(tx *gorm.DB) error {
+		if err := txLockTeam(tx, person.TeamId, &team); err != nil {
+			return err
+		}
+
+		// 队伍成员数量减一，和加入队伍一样使用相对更新
+		err := tx.Model(&model.Team{}).Where("id = ?", team.ID).
+			Updates(map[string]any{"num": gorm.Expr("num - 1"), "version": gorm.Expr("version + 1")}).Error
+		if err != nil {
+			return err
+		}
+
+		// 恢复队员信息到未加入的状态
+		person.Status = 0
+		person.TeamId = -1
+		if err := model.TxUpdatePerson(tx, person); err != nil {
+			return err
+		}
+		return model.TxRecordMembership(tx, person.OpenId, &team, model.MembershipLeft, "退出队伍")
+	})
+	if err != nil {
+		return err
+	}
+
+	captain, members := model.GetPersonsInTeam(int(team.ID)) // 获取这个人退出了以后团队中的所有成员
+	utility.SendMessageToTeam(person.Name+"已经离开了队伍", captain, members)
+	return nil
+}
+
+// Disband 队长解散队伍，并通知全部成员
+func Disband(person *model.Person) error {
+	var team model.Team
+	var captain model.Person
+	var members []model.Person
+	err := global.DB.Transaction(func(tx *gorm.DB) error {
+		// 锁定队伍后再查找团队所有用户，避免遗漏同时加入的队员
+		if err := txLockTeam(tx, person.TeamId, &team); err != nil {
+			return err
+		}
+		var err error
+		captain, members, err = model.TxGetPersonsInTeam(tx, int(team.ID))
+		if err != nil {
+			return err
+		}
+
+		// 删除团队记录
+		if err := tx.Delete(&team).Error; err != nil {
+			return err
+		}
+
+		// 还原所有队员的权限和所属团队ID
+		captain.Status = 0
+		captain.TeamId = -1
+		if err := model.TxUpdatePerson(tx, &captain); err != nil {
+			return err
+		}
+		if err := model.TxRecordMembership(tx, captain.OpenId, &team, model.MembershipLeft, "队伍解散"); err != nil {
+			return err
+		}
+		for i := range members {
+			members[i].Status = 0
+			members[i].TeamId = -1
+			if err := model.TxUpdatePerson(tx, &members[i]); err != nil {
+				return err
+			}
+			if err := model.TxRecordMembership(tx, members[i].OpenId, &team, model.MembershipLeft, "队伍解散"); err != nil {
+				return err
+			}
+		}
+
+		// 返回 nil 提交事务
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	_ = LeaveWaitlist(team.ID)
+	_ = ExpireJoinRequests(&team, "已解散")
+	utility.SendMessageToMembers(team.Name+"已经被解散", captain, members)
+	person.Status = 0
+	person.TeamId = -1
+	return nil
+}
@@ -0,0 +1,273 @@
+package teamService
+
+import (
+	"errors"
+	"slices"
+	"time"
+	"walk-server/global"
+	"walk-server/model"
+	"walk-server/utility"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 入队申请：参与者申请加入队伍，队长同意后消耗申请人一次加入次数，队伍提交、满员或解散时待处理的申请自动失效
+
+// maxPendingJoinRequests 每人同时等待处理的申请数量上限
+const maxPendingJoinRequests = 3
+
+var (
+	ErrTeamNotFound        = errors.New("team not found")
+	ErrTeamFull            = errors.New("team full")
+	ErrInTeam              = errors.New("already in a team")
+	ErrNoJoinOp            = errors.New("no join chances left")
+	ErrTeacherJoinStudent  = errors.New("teacher cannot join student team")
+	ErrJoinRequestNotFound = errors.New("join request not found")
+	ErrJoinRequestExists   = errors.New("join request already pending")
+	ErrTooManyJoinRequests = errors.New("too many pending join requests")
+)
+
+// JoinRequestInfo 队长查看的待处理申请，只包含组队需要的信息
+type JoinRequestInfo struct {
+	ID        uint      `json:"id"`
+	Name      string    `json:"name"`
+	Gender    int8      `json:"gender"`
+	College   string    `json:"college"`
+	Type      uint8     `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// MyJoinRequest 申请人查看的本人申请
+type MyJoinRequest struct {
+	ID        uint      `json:"id"`
+	TeamID    uint      `json:"team_id"`
+	TeamName  string    `json:"team_name"`
+	Status    uint8     `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// checkJoin 检查参与者能否加入队伍，captain 为队伍原来的队长
+func checkJoin(person *model.Person, team *model.Team, captain *model.Person) error {
+	if person.Status != 0 {
+		return ErrInTeam
+	}
+	if person.JoinOp == 0 {
+		return ErrNoJoinOp
+	}
+	if team.Submit {
+		return ErrTeamSubmitted
+	}
+	if team.Num >= 6 {
+		return ErrTeamFull
+	}
+	if captain.Type == 1 && person.Type == 2 {
+		return ErrTeacherJoinStudent
+	}
+	return nil
+}
+
+// RequestJoin 申请加入队伍，并通知队长处理
+func RequestJoin(person *model.Person, teamID uint) (*model.JoinRequest, error) {
+	team, err := GetTeamByID(teamID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrTeamNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	captain, _ := model.GetPersonsInTeam(int(team.ID))
+	if err := checkJoin(person, team, &captain); err != nil {
+		return nil, err
+	}
+
+	var pending []model.JoinRequest
+	err = global.DB.Where("open_id = ? AND status = ?", person.OpenId, model.JoinRequestPending).Find(&pending).Error
+	if err != nil {
+		return nil, err
+	}
+	if slices.ContainsFunc(pending, func(r model.JoinRequest) bool { return r.TeamID == team.ID }) {
+		return nil, ErrJoinRequestExists
+	}
+	if len(pending) >= maxPendingJoinRequests {
+		return nil, ErrTooManyJoinRequests
+	}
+
+	request := model.JoinRequest{
+		TeamID: team.ID,
+		OpenId: person.OpenId,
+		Status: model.JoinRequestPending,
+	}
+	if err := global.DB.Create(&request).Error; err != nil {
+		return nil, err
+	}
+
+	utility.SendMessage(person.Name+"申请加入队伍"+team.Name+"，请及时处理", nil, &captain)
+	return &request, nil
+}
+
+// ListJoinRequests 获取队伍全部待处理的申请，按申请时间排序
+func ListJoinRequests(teamID uint) ([]JoinRequestInfo, error) {
+	infos := make([]JoinRequestInfo, 0)
+	err := global.DB.Model(&model.JoinRequest{}).
+		Select("join_requests.id, people.name, people.gender, people.college, people.type, join_requests.created_at").
+		Joins("JOIN people ON people.open_id = join_requests.open_id").
+		Where("join_requests.team_id = ? AND join_requests.status = ?", teamID, model.JoinRequestPending).
+		Order("join_requests.id").Scan(&infos).Error
+	return infos, err
+}
+
+// ListMyJoinRequests 获取参与者本人的申请，最新的在前
+func ListMyJoinRequests(openID string) ([]MyJoinRequest, error) {
+	requests := make([]MyJoinRequest, 0)
+	err := global.DB.Model(&model.JoinRequest{}).
+		Select("join_requests.id, join_requests.team_id, teams.name AS team_name, join_requests.status, join_requests.created_at, join_requests.updated_at").
+		Joins("LEFT JOIN teams ON teams.id = join_requests.team_id").
+		Where("join_requests.open_id = ?", openID).
+		Order("join_requests.id DESC").Scan(&requests).Error
+	return requests, err
+}
+
+// ApproveJoinRequest 队长同意申请，申请人加入队伍并消耗一次加入次数，其余待处理的申请撤回
+// 队伍已提交或已满员时队伍的申请全部失效，申请人已经加入其他队伍时这条申请撤回
+func ApproveJoinRequest(captain *model.Person, id uint) error {
+	var request model.JoinRequest
+	var team model.Team
+	var person model.Person
+	err := global.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND team_id = ? AND status = ?", id, captain.TeamId, model.JoinRequestPending).
+			Take(&request).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrJoinRequestNotFound
+		} else if err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", request.TeamID).Take(&team).Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("open_id = ?", request.OpenId).Take(&person).Error; err != nil {
+			return err
+		}
+		if err := checkJoin(&person, &team, captain); err != nil {
+			return err
+		}
+
+		// 队伍成员数量加一
+		num := team.Num + 1
+		if err := tx.Model(&team).Update("num", num).Error; err != nil {
+			return err
+		}
+		team.Num = num
+
+		// 更新申请人的信息
+		person.Status = 1
+		person.JoinOp--
+		person.TeamId = int(team.ID)
+		if err := model.TxUpdatePersonWithVersion(tx, &person); err != nil {
+			return err
+		}
+		if err := model.TxRecordMembership(tx, person.OpenId, &team, model.MembershipJoined, "申请通过"); err != nil {
+			return err
+		}
+
+		if err := tx.Model(&request).Update("status", model.JoinRequestApproved).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.JoinRequest{}).
+			Where("open_id = ? AND status = ?", person.OpenId, model.JoinRequestPending).
+			Update("status", model.JoinRequestCancelled).Error
+	})
+
+	switch {
+	case errors.Is(err, ErrTeamSubmitted):
+		_ = ExpireJoinRequests(&team, "已提交")
+		return err
+	case errors.Is(err, ErrTeamFull):
+		_ = ExpireJoinRequests(&team, "人数已满")
+		return err
+	case errors.Is(err, ErrInTeam):
+		_ = WithdrawJoinRequests(request.OpenId)
+		return err
+	case err != nil:
+		return err
+	}
+	model.ClearPersonCache(person.OpenId)
+
+	teamCaptain, members := model.GetPersonsInTeam(int(team.ID))
+	members = slices.DeleteFunc(members, func(member model.Person) bool { return member.OpenId == person.OpenId })
+	utility.SendMessageToTeam(person.Name+"加入了团队", teamCaptain, members)
+	utility.SendMessage("队伍"+team.Name+"同意了你的入队申请", nil, &person)
+
+	if team.Num >= 6 {
+		_ = ExpireJoinRequests(&team, "人数已满")
+	}
+	return nil
+}
+
+// RejectJoinRequest 队长拒绝申请，并通知申请人
+func RejectJoinRequest(captain *model.Person, id uint) error {
+	var request model.JoinRequest
+	err := global.DB.Where("id = ? AND team_id = ? AND status = ?", id, captain.TeamId, model.JoinRequestPending).
+		Take(&request).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrJoinRequestNotFound
+	} else if err != nil {
+		return err
+	}
+	result := global.DB.Model(&model.JoinRequest{}).Where("id = ? AND status = ?", id, model.JoinRequestPending).
+		Update("status", model.JoinRequestRejected)
+	if result.Error != nil {
+		return result.Error
+	} else if result.RowsAffected == 0 {
+		return ErrJoinRequestNotFound
+	}
+
+	if person, err := model.GetPerson(request.OpenId); err == nil {
+		team, _ := model.GetTeamInfo(request.TeamID)
+		if team != nil {
+			utility.SendMessage("队伍"+team.Name+"拒绝了你的入队申请", nil, person)
+		}
+	}
+	return nil
+}
+
+// CancelJoinRequest 申请人撤回自己待处理的申请
+func CancelJoinRequest(person *model.Person, id uint) error {
+	result := global.DB.Model(&model.JoinRequest{}).
+		Where("id = ? AND open_id = ? AND status = ?", id, person.OpenId, model.JoinRequestPending).
+		Update("status", model.JoinRequestCancelled)
+	if result.Error != nil {
+		return result.Error
+	} else if result.RowsAffected == 0 {
+		return ErrJoinRequestNotFound
+	}
+	return nil
+}
+
+// WithdrawJoinRequests 参与者已经加入或创建了队伍，撤回其全部待处理的申请
+func WithdrawJoinRequests(openID string) error {
+	return global.DB.Model(&model.JoinRequest{}).
+		Where("open_id = ? AND status = ?", openID, model.JoinRequestPending).
+		Update("status", model.JoinRequestCancelled).Error
+}
+
+// ExpireJoinRequests 队伍提交、满员或解散后让待处理的申请失效，并通知申请人
+func ExpireJoinRequests(team *model.Team, reason string) error {
+	var requests []model.JoinRequest
+	err := global.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		requests, err = model.TxExpireJoinRequests(tx, team.ID)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, request := range requests {
+		if person, err := model.GetPerson(request.OpenId); err == nil {
+			utility.SendMessage("你申请加入的队伍"+team.Name+reason+"，申请已失效", nil, person)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,240 @@
+package teamService
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"sync"
+	"walk-server/global"
+	"walk-server/model"
+	"walk-server/service/dashboardService"
+	"walk-server/utility"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// 提交状态以 MySQL 中的 Team.Submit 和 Team.SubmitDay 为准
+// Redis 中的提交集合 teams 和每天各路线的剩余名额只用于提交时的准入控制，丢失后可以从 MySQL 恢复
+
+// submitMu 提交、撤销和候补提交在写 Redis 到写 MySQL 之间持有读锁，对账修复时持有写锁，
+// 避免把已经写入 Redis 但还没有写入 MySQL 的提交当作不一致
+var submitMu sync.RWMutex
+
+// LockSubmissions 暂停提交和撤销，返回解锁函数
+func LockSubmissions() func() {
+	submitMu.Lock()
+	return submitMu.Unlock
+}
+
+// ShareSubmissions 和提交一样持有读锁，调整剩余名额时使用，避免和对账修复同时修改，返回解锁函数
+func ShareSubmissions() func() {
+	submitMu.RLock()
+	return submitMu.RUnlock
+}
+
+// ErrTeamNotSubmitted 队伍未提交，不能撤销
+var ErrTeamNotSubmitted = errors.New("team not submitted")
+
+// 提交结果
+const (
+	SubmitOK        = 0 // 提交成功
+	SubmitSubmitted = 1 // 队伍已提交
+	SubmitWaitlist  = 2 // 名额已满，加入候补
+	SubmitWaiting   = 3 // 已经在候补队列中
+)
+
+// 判断是否已经提交、是否已经在候补、名额是否已满，名额已满或者已有队伍在候补时排入候补队列
+var submit = redis.NewScript(`
+local teamID = KEYS[1];
+local dailyRouteKey = KEYS[2];
+local waitlistKey = KEYS[3];
+local waitlistTeams = KEYS[4];
+
+if redis.call("sismember", "teams", teamID) == 1 then
+	return {1, 0};
+end
+
+local waiting = redis.call("hget", waitlistTeams, teamID);
+if waiting == waitlistKey then
+	local list = redis.call("lrange", waitlistKey, 0, -1);
+	for i, id in ipairs(list) do
+		if id == teamID then
+			return {3, i};
+		end
+	end
+elseif waiting then
+	-- 之前的候补已经失效
+	redis.call("lrem", waiting, 0, teamID);
+end
+
+local num = redis.call("get", dailyRouteKey);
+if tonumber(num) <= 0 or redis.call("llen", waitlistKey) > 0 then
+	local pos = redis.call("rpush", waitlistKey, teamID);
+	redis.call("hset", waitlistTeams, teamID, waitlistKey);
+	return {2, pos};
+end
+
+redis.call("decr", dailyRouteKey);
+redis.call("sadd", "teams", teamID);
+return {0, 0};
+`)
+
+// Submit 提交队伍，名额已满时加入当天该路线的候补队列，返回提交结果和候补位置
+// Redis 准入成功后写入 MySQL，写入失败时退还名额
+func Submit(team *model.Team) (int64, int64, error) {
+	submitMu.RLock()
+	defer submitMu.RUnlock()
+	if team.Submit {
+		return SubmitSubmitted, 0, nil
+	}
+
+	day := utility.GetCurrentDate()
+	dailyRouteKey := DailyRouteKey(day, team.Route)
+	keys := []string{strconv.Itoa(int(team.ID)), dailyRouteKey, WaitlistKey(dailyRouteKey), waitlistTeamsKey}
+	values, err := submit.Run(global.Rctx, global.Rdb, keys).Int64Slice()
+	if err != nil {
+		return 0, 0, err
+	}
+	if values[0] == SubmitOK {
+		if err := setSubmitted(team, true, &day); err != nil {
+			releaseQuota(team.ID, dailyRouteKey)
+			return 0, 0, err
+		}
+	}
+	return values[0], values[1], nil
+}
+
+// Rollback 撤销提交，先写入 MySQL 再清除 Redis 中的提交状态
+// 退还提交当天的名额并让给候补的队伍，管理员提交的队伍没有占用名额
+func Rollback(team *model.Team) error {
+	if !team.Submit {
+		return ErrTeamNotSubmitted
+	}
+
+	day := team.SubmitDay
+	submitMu.RLock()
+	if err := setSubmitted(team, false, nil); err != nil {
+		submitMu.RUnlock()
+		return err
+	}
+	if day == nil {
+		err := global.Rdb.SRem(global.Rctx, "teams", strconv.Itoa(int(team.ID))).Err()
+		submitMu.RUnlock()
+		return err
+	}
+	releaseQuota(team.ID, DailyRouteKey(*day, team.Route))
+	submitMu.RUnlock()
+
+	// 空出的名额让给候补的队伍
+	return PromoteWaitlist(*day, team.Route)
+}
+
+// AdminSubmit 管理员直接提交队伍，不占用名额也不记录提交日期，先移出候补队列并写入 MySQL，再写入 Redis
+func AdminSubmit(team *model.Team) error {
+	submitMu.RLock()
+	defer submitMu.RUnlock()
+	if team.Submit {
+		return nil
+	}
+
+	if err := LeaveWaitlist(team.ID); err != nil {
+		return err
+	}
+	if err := setSubmitted(team, true, nil); err != nil {
+		return err
+	}
+	// MySQL 已经写入成功，Redis 写入失败时由定时对账修复
+	if err := global.Rdb.SAdd(global.Rctx, "teams", strconv.Itoa(int(team.ID))).Err(); err != nil {
+		log.Println("提交状态写入 Redis 失败: ", err)
+	}
+	return nil
+}
+
+// setSubmitted 在 MySQL 中修改队伍的提交状态和占用名额的日期，同时更新看板的未开始人数
+// 提交后队伍待处理的入队申请失效
+func setSubmitted(team *model.Team, submit bool, day *uint8) error {
+	captain, persons := model.GetPersonsInTeam(int(team.ID))
+	persons = append(persons, captain)
+	before, after := dashboardService.Counts{}, dashboardService.Counts{}
+	before.Add(team, persons)
+
+	err := global.DB.Model(&model.Team{}).Where("id = ?", team.ID).Updates(map[string]any{
+		"submit":     submit,
+		"submit_day": day,
+		"version":    gorm.Expr("version + 1"),
+	}).Error
+	if err != nil {
+		return err
+	}
+	team.Submit = submit
+	team.SubmitDay = day
+	team.Version++
+
+	after.Add(team, persons)
+	dashboardService.Sync(before, after)
+	if team.Submit {
+		_ = ExpireJoinRequests(team, "已提交")
+	}
+	return nil
+}
+
+// releaseQuota 清除 Redis 中的提交状态并退还名额
+func releaseQuota(teamID uint, dailyRouteKey string) {
+	pipe := global.Rdb.TxPipeline()
+	pipe.SRem(global.Rctx, "teams", strconv.Itoa(int(teamID)))
+	pipe.Incr(global.Rctx, dailyRouteKey)
+	_, _ = pipe.Exec(global.Rctx)
+}
+
+// RebuildSubmitted 根据 MySQL 重建 Redis 中的提交集合，Redis 中多出的队伍会被移除
+func RebuildSubmitted() error {
+	var ids []uint
+	if err := global.DB.Model(&model.Team{}).Where("submit = ?", true).Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+
+	pipe := global.Rdb.TxPipeline()
+	pipe.Del(global.Rctx, "teams")
+	if len(ids) > 0 {
+		values := make([]any, 0, len(ids))
+		for _, id := range ids {
+			values = append(values, strconv.Itoa(int(id)))
+		}
+		pipe.SAdd(global.Rctx, "teams", values...)
+	}
+	_, err := pipe.Exec(global.Rctx)
+	return err
+}
+
+// MigrateLegacySubmitted 一次性迁移：把提交状态持久化之前只保存在 Redis 中的提交写入 MySQL
+// 这些提交无法确定占用的是哪天的名额，和管理员提交的队伍一样不记录提交日期；看板计数在启动时重新统计
+// 只在配置 reconcile.migrateLegacy 开启时运行，迁移完成后需要关闭，否则撤销后残留在 Redis 中的提交会被重新写入
+func MigrateLegacySubmitted() (int64, error) {
+	members, err := global.Rdb.SMembers(global.Rctx, "teams").Result()
+	if err != nil {
+		return 0, err
+	}
+	var legacy []uint
+	for _, member := range members {
+		id, err := strconv.Atoi(member)
+		if err == nil {
+			legacy = append(legacy, uint(id))
+		}
+	}
+
+	var migrated int64
+	err = global.DB.Transaction(func(tx *gorm.DB) error {
+		for i := 0; i < len(legacy); i += 500 {
+			end := min(i+500, len(legacy))
+			result := tx.Model(&model.Team{}).Where("id IN ? AND submit = ?", legacy[i:end], false).
+				Update("submit", true)
+			if result.Error != nil {
+				return result.Error
+			}
+			migrated += result.RowsAffected
+		}
+		return nil
+	})
+	return migrated, err
+}
@@ -1,12 +1,24 @@
 package teamService
 
 import (
+	"errors"
 	"walk-server/global"
 	"walk-server/model"
+
+	"gorm.io/gorm"
 )
 
-func Update(a model.Team) {
-	global.DB.Save(&a)
+// maxConflictRetry 版本冲突时事务的最大执行次数
+const maxConflictRetry = 3
+
+// Update 保存队伍的全部字段，同时递增版本号
+func Update(a model.Team) error {
+	return global.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("version").Save(&a).Error; err != nil {
+			return err
+		}
+		return model.BumpVersion(tx, &model.Team{ID: a.ID})
+	})
 }
 
 func Delete(a model.Team) error {
@@ -20,3 +32,16 @@ func Create(a model.Team) error {
 func UpdateCaptain(teamID int, openID string) error {
 	return global.DB.Model(&model.Team{}).Where("id = ?", teamID).Update("captain", openID).Error
 }
+
+// Transaction 执行事务，遇到版本冲突时重新执行（fn 需要在事务中重新读取数据），
+// 多次冲突后返回 model.ErrVersionConflict
+func Transaction(fn func(tx *gorm.DB) error) error {
+	var err error
+	for i := 0; i < maxConflictRetry; i++ {
+		err = global.DB.Transaction(fn)
+		if !errors.Is(err, model.ErrVersionConflict) {
+			return err
+		}
+	}
+	return err
+}
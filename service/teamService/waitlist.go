@@ -0,0 +1,152 @@
+package teamService
+
+import (
+	"errors"
+	"strconv"
+	"walk-server/global"
+	"walk-server/model"
+	"walk-server/utility"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 候补队列：每天每条路线一个 list，提交时名额已满的队伍按先后顺序排队，名额空出时自动提交
+const waitlistTeamsKey = "waitlist:teams" // hash，队伍ID -> 所在的候补队列
+
+// 有剩余名额时取出候补队列中的第一支队伍并提交，已经提交过的队伍返回空字符串
+var promote = redis.NewScript(`
+local dailyRouteKey = KEYS[1];
+local waitlistKey = KEYS[2];
+local waitlistTeams = KEYS[3];
+
+local num = redis.call("get", dailyRouteKey);
+if not num or tonumber(num) <= 0 then
+	return false;
+end
+
+local teamID = redis.call("lpop", waitlistKey);
+if not teamID then
+	return false;
+end
+redis.call("hdel", waitlistTeams, teamID);
+if redis.call("sismember", "teams", teamID) == 1 then
+	return "";
+end
+
+redis.call("decr", dailyRouteKey);
+redis.call("sadd", "teams", teamID);
+return teamID;
+`)
+
+// DailyRouteKey 某天某条路线的剩余名额
+func DailyRouteKey(day uint8, route uint8) string {
+	return strconv.Itoa(int(day)*10 + int(route))
+}
+
+// WaitlistKey 名额对应的候补队列
+func WaitlistKey(dailyRouteKey string) string {
+	return "waitlist:" + dailyRouteKey
+}
+
+// GetWaitlistPosition 获取队伍在候补队列中的位置，不在候补中时返回 0
+func GetWaitlistPosition(teamID uint) (int64, error) {
+	id := strconv.Itoa(int(teamID))
+	key, err := global.Rdb.HGet(global.Rctx, waitlistTeamsKey, id).Result()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	pos, err := global.Rdb.LPos(global.Rctx, key, id, redis.LPosArgs{}).Result()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return pos + 1, err
+}
+
+// LeaveWaitlist 把队伍移出候补队列
+func LeaveWaitlist(teamID uint) error {
+	id := strconv.Itoa(int(teamID))
+	key, err := global.Rdb.HGet(global.Rctx, waitlistTeamsKey, id).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	pipe := global.Rdb.TxPipeline()
+	pipe.LRem(global.Rctx, key, 0, id)
+	pipe.HDel(global.Rctx, waitlistTeamsKey, id)
+	_, err = pipe.Exec(global.Rctx)
+	return err
+}
+
+// PromoteWaitlist 有名额空出时按顺序自动提交候补的队伍，并通知队伍成员
+// 候补期间人数不足或者更换了路线的队伍会被移出候补、退还名额并收到通知，写入数据库失败的队伍放回候补队列最前面
+func PromoteWaitlist(day uint8, route uint8) error {
+	dailyRouteKey := DailyRouteKey(day, route)
+	keys := []string{dailyRouteKey, WaitlistKey(dailyRouteKey), waitlistTeamsKey}
+	for {
+		done, notify, err := promoteNext(day, route, keys)
+		if notify != nil {
+			notify()
+		}
+		if done || err != nil {
+			return err
+		}
+	}
+}
+
+// promoteNext 提交候补队列中的下一支队伍，没有剩余名额或者没有候补的队伍时返回 true
+// 返回的 notify 在释放锁之后发送通知
+func promoteNext(day uint8, route uint8, keys []string) (bool, func(), error) {
+	submitMu.RLock()
+	defer submitMu.RUnlock()
+
+	dailyRouteKey := keys[0]
+	teamID, err := promote.Run(global.Rctx, global.Rdb, keys).Text()
+	if errors.Is(err, redis.Nil) {
+		return true, nil, nil // 没有剩余名额或者没有候补的队伍
+	} else if err != nil {
+		return true, nil, err
+	}
+	if teamID == "" {
+		return false, nil, nil
+	}
+
+	id, _ := strconv.Atoi(teamID)
+	team, err := GetTeamByID(uint(id))
+	if err != nil {
+		// 队伍已经解散
+		releaseQuota(uint(id), dailyRouteKey)
+		return false, nil, nil
+	}
+	captain, members := model.GetPersonsInTeam(id)
+	if team.Route != route || team.Num < 4 {
+		releaseQuota(team.ID, dailyRouteKey)
+		reason := "队伍人数不足四人"
+		if team.Route != route {
+			reason = "队伍已更换路线"
+		}
+		return false, func() {
+			utility.SendMessageToTeam("候补失效，"+reason+"，队伍"+team.Name+"已移出候补，请重新提交", captain, members)
+		}, nil
+	}
+	if err := setSubmitted(team, true, &day); err != nil {
+		// 退还名额并放回候补队列的最前面，等下次有名额空出时重试
+		releaseQuota(team.ID, dailyRouteKey)
+		requeue(teamID, keys[1])
+		return true, nil, err
+	}
+
+	return false, func() {
+		utility.SendMessageToTeam("候补成功，队伍"+team.Name+"已自动提交", captain, members)
+	}, nil
+}
+
+// requeue 把队伍放回候补队列的最前面
+func requeue(teamID string, waitlistKey string) {
+	pipe := global.Rdb.TxPipeline()
+	pipe.LPush(global.Rctx, waitlistKey, teamID)
+	pipe.HSet(global.Rctx, waitlistTeamsKey, teamID, waitlistKey)
+	_, _ = pipe.Exec(global.Rctx)
+}
@@ -3,11 +3,14 @@ package userService
 import (
 	"walk-server/global"
 	"walk-server/model"
+	"walk-server/privacy"
+
+	"gorm.io/gorm"
 )
 
 func GetUserByID(id string) (*model.Person, error) {
 	var person model.Person
-	result := global.DB.Where("identity = ?", id).First(&person)
+	result := global.DB.Where("identity_index = ?", privacy.BlindIndex(id)).First(&person)
 	return &person, result.Error
 }
 
@@ -22,3 +25,17 @@ func GetUsersByTeamID(teamID uint) ([]model.Person, error) {
 	result := global.DB.Where("team_id = ?", teamID).Find(&users)
 	return users, result.Error
 }
+
+// TxGetUserByOpenID 在事务中读取用户，不经过缓存
+func TxGetUserByOpenID(tx *gorm.DB, oid string) (*model.Person, error) {
+	var person model.Person
+	result := tx.Where("open_id = ?", oid).First(&person)
+	return &person, result.Error
+}
+
+// TxGetUsersByTeamID 在事务中读取队伍成员，不经过缓存
+func TxGetUsersByTeamID(tx *gorm.DB, teamID uint) ([]model.Person, error) {
+	var users []model.Person
+	result := tx.Where("team_id = ?", teamID).Find(&users)
+	return users, result.Error
+}
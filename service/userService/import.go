@@ -0,0 +1,55 @@
+package userService
+
+import (
+	"walk-server/global"
+	"walk-server/model"
+	"walk-server/privacy"
+
+	"gorm.io/gorm"
+)
+
+// 导入成员在绑定微信前使用的占位 OpenID，登录后由 Set 替换为真实的 OpenID
+const ImportedOpenIDPrefix = "imported:"
+
+// GetConflictUsers 获取身份证号、电话或学号已被占用的成员，身份证号通过盲索引比较
+func GetConflictUsers(identities []string, tels []string, stuIDs []string) ([]model.Person, error) {
+	indexes := make([]string, 0, len(identities))
+	for _, identity := range identities {
+		indexes = append(indexes, privacy.BlindIndex(identity))
+	}
+	var persons []model.Person
+	query := global.DB.Select("identity_index", "tel", "stu_id").Where("identity_index IN ?", indexes)
+	if len(tels) > 0 {
+		query = query.Or("tel IN ?", tels)
+	}
+	if len(stuIDs) > 0 {
+		query = query.Or("stu_id IN ?", stuIDs)
+	}
+	result := query.Find(&persons)
+	return persons, result.Error
+}
+
+// ImportUsers 在一个事务中写入导入的成员，没有学号的成员学号保存为 NULL
+func ImportUsers(persons []model.Person) error {
+	var withStuID, withoutStuID []model.Person
+	for _, p := range persons {
+		if p.StuId == "" {
+			withoutStuID = append(withoutStuID, p)
+		} else {
+			withStuID = append(withStuID, p)
+		}
+	}
+	return global.DB.Transaction(func(tx *gorm.DB) error {
+		if len(withStuID) > 0 {
+			if err := tx.CreateInBatches(withStuID, 100).Error; err != nil {
+				return err
+			}
+		}
+		if len(withoutStuID) > 0 {
+			if err := tx.Omit("stu_id").CreateInBatches(withoutStuID, 100).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,56 @@
+package userService
+
+import (
+	"walk-server/global"
+	"walk-server/model"
+	"walk-server/utility"
+
+	"gorm.io/gorm"
+)
+
+// 注销后的用户使用的占位 OpenID 前缀
+const DeletedOpenIDPrefix = "deleted:"
+
+// Anonymize 注销用户，保留性别、校区、学院和类型用于统计，清除其余个人信息并删除用户的消息和队伍历史
+// 调用前用户需要已经离开队伍
+func Anonymize(openID string) error {
+	placeholder, err := utility.RandomToken(16)
+	if err != nil {
+		return err
+	}
+	// 电话不能为空且唯一，使用随机值占位
+	tel, err := utility.RandomToken(10)
+	if err != nil {
+		return err
+	}
+
+	err = global.DB.Transaction(func(tx *gorm.DB) error {
+		// 直接更新原始列，学号和盲索引需要置为 NULL 以免违反唯一约束
+		// 旧版本的身份证号列上有唯一索引，使用和 OpenID 相同的随机值占位
+		err := tx.Table("people").Where("open_id = ?", openID).UpdateColumns(map[string]interface{}{
+			"open_id":        DeletedOpenIDPrefix + placeholder,
+			"name":           "已注销",
+			"stu_id":         nil,
+			"identity":       DeletedOpenIDPrefix + placeholder,
+			"identity_index": nil,
+			"qq":             "",
+			"wechat":         "",
+			"tel":            tel,
+			"status":         0,
+			"team_id":        -1,
+		}).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("open_id = ?", openID).Delete(&model.Membership{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("receiver_open_id = ? OR sender_open_id = ?", openID, openID).Delete(&model.Message{}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	model.ClearPersonCache(openID)
+	return nil
+}
@@ -4,8 +4,8 @@ import (
 	"walk-server/model"
 )
 
-func Update(a model.Person) {
-	model.UpdatePerson(a.OpenId, &a)
+func Update(a model.Person) error {
+	return model.UpdatePerson(a.OpenId, &a)
 }
 
 func Set(open_id string, a model.Person) error {
@@ -0,0 +1,22 @@
+// Package state 定义队伍和队员的状态机，所有状态变化都需要经过这里的校验
+package state
+
+import "errors"
+
+// ErrIllegalTransition 不允许的状态变化
+var ErrIllegalTransition = errors.New("illegal state transition")
+
+// TransitionError 记录不允许的状态变化，可以用 errors.Is(err, ErrIllegalTransition) 判断
+type TransitionError struct {
+	Kind string
+	From string
+	To   string
+}
+
+func (e *TransitionError) Error() string {
+	return e.Kind + "状态不能从" + e.From + "变为" + e.To
+}
+
+func (e *TransitionError) Unwrap() error {
+	return ErrIllegalTransition
+}
@@ -0,0 +1,95 @@
+package state
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTeamTransitions(t *testing.T) {
+	tests := []struct {
+		from, to TeamStatus
+		allowed  bool
+	}{
+		{TeamNotStarted, TeamStarted, true},
+		{TeamNotStarted, TeamIncomplete, true},
+		{TeamNotStarted, TeamInProgress, false},
+		{TeamNotStarted, TeamFinished, false},
+		{TeamStarted, TeamStarted, true},
+		{TeamStarted, TeamInProgress, true},
+		{TeamStarted, TeamFinished, true},
+		{TeamStarted, TeamNotStarted, false},
+		{TeamInProgress, TeamInProgress, true},
+		{TeamInProgress, TeamIncomplete, true},
+		{TeamInProgress, TeamFinished, true},
+		{TeamInProgress, TeamStarted, false},
+		{TeamInProgress, TeamNotStarted, false},
+		{TeamIncomplete, TeamInProgress, false},
+		{TeamIncomplete, TeamFinished, false},
+		{TeamFinished, TeamInProgress, false},
+		{TeamFinished, TeamIncomplete, false},
+		{TeamStatus(0), TeamStarted, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.from.String()+"->"+tt.to.String(), func(t *testing.T) {
+			if got := tt.from.CanTransit(tt.to); got != tt.allowed {
+				t.Fatalf("CanTransit = %v, want %v", got, tt.allowed)
+			}
+			err := tt.from.Check(tt.to)
+			if tt.allowed && err != nil {
+				t.Fatalf("Check returned %v", err)
+			}
+			if !tt.allowed && !errors.Is(err, ErrIllegalTransition) {
+				t.Fatalf("Check returned %v, want ErrIllegalTransition", err)
+			}
+		})
+	}
+}
+
+func TestWalkTransitions(t *testing.T) {
+	tests := []struct {
+		from, to WalkStatus
+		allowed  bool
+	}{
+		{WalkNotStarted, WalkNotStarted, true},
+		{WalkNotStarted, WalkScanned, true},
+		{WalkNotStarted, WalkAbandoned, true},
+		{WalkNotStarted, WalkInProgress, false},
+		{WalkNotStarted, WalkFinished, false},
+		{WalkScanned, WalkInProgress, true},
+		{WalkScanned, WalkFinished, true},
+		{WalkScanned, WalkNotStarted, true},
+		{WalkInProgress, WalkScanned, true},
+		{WalkInProgress, WalkAbandoned, true},
+		{WalkInProgress, WalkFinished, true},
+		{WalkInProgress, WalkInProgress, false},
+		{WalkInProgress, WalkNotStarted, false},
+		{WalkAbandoned, WalkScanned, true},
+		{WalkAbandoned, WalkNotStarted, true},
+		{WalkAbandoned, WalkInProgress, false},
+		{WalkAbandoned, WalkFinished, false},
+		{WalkFinished, WalkInProgress, false},
+		{WalkFinished, WalkScanned, false},
+		{WalkFinished, WalkAbandoned, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.from.String()+"->"+tt.to.String(), func(t *testing.T) {
+			if got := tt.from.CanTransit(tt.to); got != tt.allowed {
+				t.Fatalf("CanTransit = %v, want %v", got, tt.allowed)
+			}
+			err := tt.from.Check(tt.to)
+			if tt.allowed && err != nil {
+				t.Fatalf("Check returned %v", err)
+			}
+			if !tt.allowed && !errors.Is(err, ErrIllegalTransition) {
+				t.Fatalf("Check returned %v, want ErrIllegalTransition", err)
+			}
+		})
+	}
+}
+
+func TestTransitionErrorMessage(t *testing.T) {
+	err := TeamFinished.Check(TeamInProgress)
+	if err == nil || err.Error() != "队伍状态不能从完成变为进行中" {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,57 @@
+package state
+
+import (
+	"fmt"
+	"slices"
+)
+
+// TeamStatus 队伍状态
+type TeamStatus uint8
+
+const (
+	TeamNotStarted TeamStatus = 1 // 未开始
+	TeamInProgress TeamStatus = 2 // 进行中
+	TeamIncomplete TeamStatus = 3 // 未完成（全员下撤或终点确认未完成）
+	TeamFinished   TeamStatus = 4 // 完成
+	TeamStarted    TeamStatus = 5 // 起点扫码成功
+)
+
+var teamStatusNames = map[TeamStatus]string{
+	TeamNotStarted: "未开始",
+	TeamInProgress: "进行中",
+	TeamIncomplete: "未完成",
+	TeamFinished:   "完成",
+	TeamStarted:    "起点扫码成功",
+}
+
+// teamTransitions 队伍状态允许的变化，未列出的状态为终态
+var teamTransitions = map[TeamStatus][]TeamStatus{
+	TeamNotStarted: {TeamStarted, TeamIncomplete},
+	TeamStarted:    {TeamStarted, TeamInProgress, TeamIncomplete, TeamFinished},
+	TeamInProgress: {TeamInProgress, TeamIncomplete, TeamFinished},
+}
+
+func (s TeamStatus) String() string {
+	if name, ok := teamStatusNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("未知状态(%d)", uint8(s))
+}
+
+// IsOver 队伍是否已经结束
+func (s TeamStatus) IsOver() bool {
+	return s == TeamIncomplete || s == TeamFinished
+}
+
+// CanTransit 判断队伍状态能否变为 next
+func (s TeamStatus) CanTransit(next TeamStatus) bool {
+	return slices.Contains(teamTransitions[s], next)
+}
+
+// Check 校验队伍状态能否变为 next，不能时返回 ErrIllegalTransition
+func (s TeamStatus) Check(next TeamStatus) error {
+	if !s.CanTransit(next) {
+		return &TransitionError{Kind: "队伍", From: s.String(), To: next.String()}
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package state
+
+import (
+	"fmt"
+	"slices"
+)
+
+// WalkStatus 队员的毅行状态
+type WalkStatus uint8
+
+const (
+	WalkNotStarted WalkStatus = 1 // 未开始
+	WalkInProgress WalkStatus = 2 // 进行中
+	WalkScanned    WalkStatus = 3 // 扫码成功（在点位确认到场，等待队伍扫码）
+	WalkAbandoned  WalkStatus = 4 // 放弃
+	WalkFinished   WalkStatus = 5 // 完成
+)
+
+var walkStatusNames = map[WalkStatus]string{
+	WalkNotStarted: "未开始",
+	WalkInProgress: "进行中",
+	WalkScanned:    "扫码成功",
+	WalkAbandoned:  "放弃",
+	WalkFinished:   "完成",
+}
+
+// walkTransitions 队员状态允许的变化，未列出的状态为终态
+// 放弃的队员可以被重新确认到场，用于更正误操作；队伍重组时未出发的队员回到未开始
+var walkTransitions = map[WalkStatus][]WalkStatus{
+	WalkNotStarted: {WalkNotStarted, WalkScanned, WalkAbandoned},
+	WalkInProgress: {WalkScanned, WalkAbandoned, WalkFinished},
+	WalkScanned:    {WalkNotStarted, WalkInProgress, WalkScanned, WalkAbandoned, WalkFinished},
+	WalkAbandoned:  {WalkNotStarted, WalkScanned, WalkAbandoned},
+}
+
+func (s WalkStatus) String() string {
+	if name, ok := walkStatusNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("未知状态(%d)", uint8(s))
+}
+
+// IsWalking 队员是否仍在队伍中行进（进行中或刚在点位扫码）
+func (s WalkStatus) IsWalking() bool {
+	return s == WalkInProgress || s == WalkScanned
+}
+
+// CanTransit 判断队员状态能否变为 next
+func (s WalkStatus) CanTransit(next WalkStatus) bool {
+	return slices.Contains(walkTransitions[s], next)
+}
+
+// Check 校验队员状态能否变为 next，不能时返回 ErrIllegalTransition
+func (s WalkStatus) Check(next WalkStatus) error {
+	if !s.CanTransit(next) {
+		return &TransitionError{Kind: "队员", From: s.String(), To: next.String()}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package utility
+
+import (
+	"strings"
+	"walk-server/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 鉴权中间件写入 gin 上下文的数据
+const (
+	contextJwt    = "jwt"
+	contextPerson = "person"
+	contextAdmin  = "admin"
+)
+
+// BearerToken 从 Authorization 请求头中取出 token，请求头缺失或格式不对时返回 false
+func BearerToken(context *gin.Context) (string, bool) {
+	return TrimBearer(context.GetHeader("Authorization"))
+}
+
+// TrimBearer 去掉 "Bearer " 前缀取出 token，格式不对时返回 false
+func TrimBearer(value string) (string, bool) {
+	scheme, token, ok := strings.Cut(value, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return token, true
+}
+
+// SetJwtData 保存鉴权中间件解析出的 jwt 数据
+func SetJwtData(context *gin.Context, jwtData *JwtData) {
+	context.Set(contextJwt, jwtData)
+}
+
+// GetJwtData 从控制器上下文中获取 jwt 数据，需要放在鉴权中间件之后
+func GetJwtData(context *gin.Context) *JwtData {
+	value, _ := context.Get(contextJwt)
+	jwtData, _ := value.(*JwtData)
+	return jwtData
+}
+
+// SetPerson 保存 IsRegistered 读取的当前用户
+func SetPerson(context *gin.Context, person *model.Person) {
+	context.Set(contextPerson, person)
+}
+
+// GetPerson 获取当前请求的用户，需要放在 IsRegistered 之后
+func GetPerson(context *gin.Context) *model.Person {
+	value, _ := context.Get(contextPerson)
+	person, _ := value.(*model.Person)
+	return person
+}
+
+// SetAdmin 保存 CheckAdmin 读取的当前管理员
+func SetAdmin(context *gin.Context, admin *model.Admin) {
+	context.Set(contextAdmin, admin)
+}
+
+// GetAdmin 获取当前请求的管理员，需要放在 CheckAdmin 之后
+func GetAdmin(context *gin.Context) *model.Admin {
+	value, _ := context.Get(contextAdmin)
+	admin, _ := value.(*model.Admin)
+	return admin
+}
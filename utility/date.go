@@ -24,6 +24,16 @@ func CanOpenApi() bool {
 	return time.Now().After(startTime) && time.Now().Hour() >= 6
 }
 
+// IsExpired 报名是否已经截止
+func IsExpired() bool {
+	expiredTime, _ := time.ParseInLocation(
+		time.DateTime,
+		global.Config.GetString("expiredDate"),
+		time.Local,
+	)
+	return !time.Now().Before(expiredTime)
+}
+
 func CanSubmit() bool {
 	return time.Now().Hour() >= 12
 }
@@ -1,11 +1,15 @@
 package utility
 
 import (
+	"encoding/csv"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
+	"unicode/utf8"
 
 	"log"
 
@@ -86,13 +90,12 @@ var (
 	centeredStyleID int
 )
 
-// CreateExcelFile 生成 Excel 文件
-func CreateExcelFile(data File, fileName, filePath, host string) (string, error) {
-	if err := validateFileData(data); err != nil {
-		return "", fmt.Errorf("无效的文件数据: %w", err)
+// CreateExcelFile 以随机文件名保存 Excel 文件，返回带签名和有效期的下载链接，fileName 为下载时显示的文件名
+func CreateExcelFile(data File, fileName string) (string, error) {
+	f, err := newExcelFile(data)
+	if err != nil {
+		return "", err
 	}
-
-	f := excelize.NewFile()
 	// 使用 defer 确保在任何情况下都能关闭文件
 	defer func() {
 		if err := f.Close(); err != nil {
@@ -100,38 +103,45 @@ func CreateExcelFile(data File, fileName, filePath, host string) (string, error)
 		}
 	}()
 
-	// 初始化样式
-	if err := initStyles(f); err != nil {
-		return "", fmt.Errorf("初始化样式失败: %w", err)
+	if err := ensureDirExists(FileDir()); err != nil {
+		return "", fmt.Errorf("确保目录存在失败: %w", err)
 	}
 
-	// 处理每个工作表
-	for i, sheet := range data.Sheets {
-		if err := createSheet(f, sheet, i); err != nil {
-			return "", fmt.Errorf("创建工作表 '%s' 失败: %w", sheet.Name, err)
-		}
+	id, fullPath, err := newFilePath(fileName)
+	if err != nil {
+		return "", fmt.Errorf("生成文件名失败: %w", err)
+	}
+	if err := f.SaveAs(fullPath); err != nil {
+		return "", fmt.Errorf("保存 Excel 文件失败: %w", err)
 	}
 
-	f.SetActiveSheet(0)
+	return FileURL(id, fileName), nil
+}
 
-	fullPath := filepath.Join(filePath, fileName)
-	if err := ensureDirExists(filePath); err != nil {
-		return "", fmt.Errorf("确保目录存在失败: %w", err)
+// newExcelFile 校验数据并生成包含全部工作表的 Excel 文件，使用完毕后需要关闭
+func newExcelFile(data File) (*excelize.File, error) {
+	if err := validateFileData(data); err != nil {
+		return nil, fmt.Errorf("无效的文件数据: %w", err)
 	}
 
-	if err := removeOldFile(fullPath); err != nil {
-		log.Printf("删除旧文件失败: %v", err)
-	}
+	f := excelize.NewFile()
 
-	if err := f.SaveAs(fullPath); err != nil {
-		return "", fmt.Errorf("保存 Excel 文件失败: %w", err)
+	// 初始化样式
+	if err := initStyles(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("初始化样式失败: %w", err)
 	}
 
-	if !strings.HasSuffix(host, DefaultHostSuffix) {
-		host += DefaultHostSuffix
+	// 处理每个工作表
+	for i, sheet := range data.Sheets {
+		if err := createSheet(f, sheet, i); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("创建工作表 '%s' 失败: %w", sheet.Name, err)
+		}
 	}
 
-	return host + fullPath, nil
+	f.SetActiveSheet(0)
+	return f, nil
 }
 
 // initStyles 初始化样式
@@ -364,6 +374,38 @@ func writeRows(sw *excelize.StreamWriter, rows [][]any) error {
 	return nil
 }
 
+// sheetNameReplacer 替换 Excel 工作表名称中不允许的字符
+var sheetNameReplacer = strings.NewReplacer(":", "_", "\\", "_", "/", "_", "?", "_", "*", "_", "[", "(", "]", ")")
+
+// SheetNames 把名称转换为合法的工作表名称：替换不允许的字符，按字符数截断到 31 个字符，
+// 截断后重复的名称（不区分大小写）在末尾加上序号
+func SheetNames(names []string) []string {
+	result := make([]string, 0, len(names))
+	used := make(map[string]bool, len(names))
+	for _, name := range names {
+		name = strings.Trim(sheetNameReplacer.Replace(name), "'")
+		if name == "" {
+			name = DefaultSheetName
+		}
+		candidate := truncateRunes(name, MaxSheetNameLen)
+		for i := 2; used[strings.ToLower(candidate)]; i++ {
+			suffix := "(" + strconv.Itoa(i) + ")"
+			candidate = truncateRunes(name, MaxSheetNameLen-len(suffix)) + suffix
+		}
+		used[strings.ToLower(candidate)] = true
+		result = append(result, candidate)
+	}
+	return result
+}
+
+// truncateRunes 按字符数截断字符串
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
+
 // validateFileData 验证文件数据
 func validateFileData(data File) error {
 	if len(data.Sheets) == 0 {
@@ -373,7 +415,7 @@ func validateFileData(data File) error {
 		if len(sheet.Name) == 0 {
 			return fmt.Errorf("%w: 工作表名称不能为空", ErrInvalidFileData)
 		}
-		if len(sheet.Name) > MaxSheetNameLen {
+		if utf8.RuneCountInString(sheet.Name) > MaxSheetNameLen {
 			return fmt.Errorf("%w: 工作表名称超过最大长度 %d", ErrInvalidFileData, MaxSheetNameLen)
 		}
 		if len(sheet.Headers) == 0 {
@@ -403,12 +445,31 @@ func ensureDirExists(filePath string) error {
 	return nil
 }
 
-// removeOldFile 删除旧文件
-func removeOldFile(fullPath string) error {
-	if _, err := os.Stat(fullPath); err == nil {
-		if err := os.Remove(fullPath); err != nil {
-			return fmt.Errorf("%w: %v", ErrRemoveFileFailed, err)
+// ErrUnsupportedFile 不支持的上传文件格式
+var ErrUnsupportedFile = errors.New("只支持 xlsx 和 csv 文件")
+
+// ReadTable 读取上传的 Excel 第一个工作表或 CSV 文件的全部行，第一行为列名
+func ReadTable(fileName string, r io.Reader) ([][]string, error) {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".xlsx":
+		f, err := excelize.OpenReader(r)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return f.GetRows(f.GetSheetName(0))
+	case ".csv":
+		reader := csv.NewReader(r)
+		reader.FieldsPerRecord = -1
+		rows, err := reader.ReadAll()
+		if err != nil {
+			return nil, err
 		}
+		// 去掉 Excel 导出 CSV 时带的 BOM
+		if len(rows) > 0 && len(rows[0]) > 0 {
+			rows[0][0] = strings.TrimPrefix(rows[0][0], "\ufeff")
+		}
+		return rows, nil
 	}
-	return nil
+	return nil, ErrUnsupportedFile
 }
@@ -0,0 +1,43 @@
+package utility
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSheetNames(t *testing.T) {
+	long := strings.Repeat("莫干山", 12)
+	tests := []struct {
+		name  string
+		input []string
+		want  []string
+	}{
+		{"unchanged", []string{"朝晖-起点", "屏峰半程-终点"}, []string{"朝晖-起点", "屏峰半程-终点"}},
+		{"invalid characters", []string{"a/b:c?[d]"}, []string{"a_b_c_(d)"}},
+		{"empty", []string{""}, []string{DefaultSheetName}},
+		{"truncate by runes", []string{long}, []string{string([]rune(long)[:MaxSheetNameLen])}},
+		{
+			"deduplicate after truncation",
+			[]string{long + "一", long + "二"},
+			[]string{string([]rune(long)[:MaxSheetNameLen]), string([]rune(long)[:MaxSheetNameLen-3]) + "(2)"},
+		},
+		{"case insensitive duplicate", []string{"Day", "day"}, []string{"Day", "day(2)"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SheetNames(tt.input)
+			if len(got) != len(tt.want) {
+				t.Fatalf("SheetNames(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("SheetNames(%q) = %q, want %q", tt.input, got, tt.want)
+				}
+				if utf8.RuneCountInString(got[i]) > MaxSheetNameLen {
+					t.Fatalf("sheet name %q longer than %d characters", got[i], MaxSheetNameLen)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package utility
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"walk-server/global"
+)
+
+// 生成的文件以随机文件名保存，只能通过带签名和有效期的链接下载，超过保留时间后删除
+const (
+	DefaultFileDir       = "./file"
+	DefaultFileExpire    = 30 * time.Minute // 下载链接有效期
+	DefaultFileRetention = 24 * time.Hour   // 文件保留时间
+)
+
+var (
+	ErrFileLinkInvalid = errors.New("下载链接无效")
+	ErrFileLinkExpired = errors.New("下载链接已过期")
+)
+
+var fileIDRegexp = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// FileDir 生成文件的保存目录
+func FileDir() string {
+	if dir := global.Config.GetString("file.dir"); dir != "" {
+		return dir
+	}
+	return DefaultFileDir
+}
+
+func fileExpire() time.Duration {
+	if minutes := global.Config.GetInt("file.expire"); minutes > 0 {
+		return time.Duration(minutes) * time.Minute
+	}
+	return DefaultFileExpire
+}
+
+func fileRetention() time.Duration {
+	if hours := global.Config.GetInt("file.retention"); hours > 0 {
+		return time.Duration(hours) * time.Hour
+	}
+	return DefaultFileRetention
+}
+
+// newFilePath 为要生成的文件分配随机 ID 和保存路径
+func newFilePath(name string) (string, string, error) {
+	id, err := RandomToken(16)
+	if err != nil {
+		return "", "", err
+	}
+	return id, filepath.Join(FileDir(), id+filepath.Ext(name)), nil
+}
+
+// fileKey 由 server.JWTSecret 派生下载链接的签名密钥，和 jwt 签名使用不同的密钥
+func fileKey() []byte {
+	mac := hmac.New(sha256.New, []byte(global.Config.GetString("server.JWTSecret")))
+	mac.Write([]byte("file-link"))
+	return mac.Sum(nil)
+}
+
+// signFile 计算下载链接的签名，文件 ID、下载文件名和过期时间都参与签名
+func signFile(id string, name string, expires int64) string {
+	mac := hmac.New(sha256.New, fileKey())
+	mac.Write([]byte(id + "\n" + name + "\n" + strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// FileURL 生成文件的签名下载链接，name 为下载时显示的文件名
+func FileURL(id string, name string) string {
+	expires := time.Now().Add(fileExpire()).Unix()
+	query := url.Values{
+		"name":    {name},
+		"expires": {strconv.FormatInt(expires, 10)},
+		"sign":    {signFile(id, name, expires)},
+	}
+	host := global.Config.GetString("frontend.url")
+	if !strings.HasSuffix(host, DefaultHostSuffix) {
+		host += DefaultHostSuffix
+	}
+	return host + "api/v1/file/" + id + "?" + query.Encode()
+}
+
+// VerifyFileLink 校验下载链接，返回文件的保存路径
+func VerifyFileLink(id string, name string, expires string, sign string) (string, error) {
+	if !fileIDRegexp.MatchString(id) {
+		return "", ErrFileLinkInvalid
+	}
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return "", ErrFileLinkInvalid
+	}
+	if !hmac.Equal([]byte(sign), []byte(signFile(id, name, expiresAt))) {
+		return "", ErrFileLinkInvalid
+	}
+	if time.Now().Unix() > expiresAt {
+		return "", ErrFileLinkExpired
+	}
+	return filepath.Join(FileDir(), id+filepath.Ext(name)), nil
+}
+
+// CleanFiles 删除超过保留时间的生成文件，某个文件删除失败时继续删除其余文件
+func CleanFiles() error {
+	entries, err := os.ReadDir(FileDir())
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	deadline := time.Now().Add(-fileRetention())
+	var errs []error
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || info.ModTime().After(deadline) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(FileDir(), entry.Name())); err != nil {
+			errs = append(errs, fmt.Errorf("%w: %v", ErrRemoveFileFailed, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,39 @@
+package initial
+
+import (
+	"log"
+	"walk-server/global"
+	"walk-server/model"
+	"walk-server/utility"
+)
+
+// AdminInit 迁移旧的明文密码，并将配置文件中列出的账号设为超级管理员
+func AdminInit() {
+	var admins []model.Admin
+	if err := global.DB.Select("id", "password").Find(&admins).Error; err != nil {
+		log.Fatal("管理员读取错误: ", err)
+	}
+	for _, admin := range admins {
+		if utility.IsPasswordHashed(admin.Password) {
+			continue
+		}
+		hash, err := utility.HashPassword(admin.Password)
+		if err != nil {
+			log.Fatal("管理员密码迁移错误: ", err)
+		}
+		if err := global.DB.Model(&model.Admin{}).Where("id = ?", admin.ID).Update("password", hash).Error; err != nil {
+			log.Fatal("管理员密码迁移错误: ", err)
+		}
+	}
+
+	accounts := global.Config.GetStringSlice("admin.super")
+	if len(accounts) == 0 {
+		return
+	}
+	err := global.DB.Model(&model.Admin{}).
+		Where("account IN ?", accounts).
+		Update("role", model.RoleSuper).Error
+	if err != nil {
+		log.Fatal("超级管理员初始化错误: ", err)
+	}
+}
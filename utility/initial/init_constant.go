@@ -2,17 +2,168 @@ package initial
 
 import (
 	"log"
-	"walk-server/constant"
 	"walk-server/global"
+	"walk-server/model"
+	"walk-server/service/routeService"
+
+	"gorm.io/gorm"
 )
 
+// 数据库中没有路线数据时写入的默认路线
+var defaultRoutes = []model.Route{
+	{ID: 1, Code: "zh", Name: "朝晖", Campus: 1},
+	{ID: 2, Code: "pfHalf", Name: "屏峰半程", Campus: 2},
+	{ID: 3, Code: "pfAll", Name: "屏峰全程", Campus: 2},
+	{ID: 4, Code: "mgsHalf", Name: "莫干山半程", Campus: 3},
+	{ID: 5, Code: "mgsAll", Name: "莫干山全程", Campus: 3},
+}
+
+// 默认地点，多条路线经过的地点只声明一次
+var defaultSites = []model.Site{
+	{ID: 1, Name: "起点", Campus: 1},
+	{ID: 2, Name: "上塘映翠", Campus: 1},
+	{ID: 3, Name: "京杭大运河", Campus: 1},
+	{ID: 4, Name: "西湖文化广场", Campus: 1},
+	{ID: 5, Name: "中国海事", Campus: 1},
+	{ID: 6, Name: "忠亭", Campus: 1},
+	{ID: 7, Name: "德胜运河驿站", Campus: 1},
+	{ID: 8, Name: "终点", Campus: 1},
+	{ID: 9, Name: "起点", Campus: 2},
+	{ID: 10, Name: "金莲寺", Campus: 2},
+	{ID: 11, Name: "老焦山", Campus: 2},
+	{ID: 12, Name: "龙门坎", Campus: 2},
+	{ID: 13, Name: "慈母桥", Campus: 2},
+	{ID: 14, Name: "古樟树公园", Campus: 2},
+	{ID: 15, Name: "屏峰山", Campus: 2},
+	{ID: 16, Name: "屏峰善院", Campus: 2},
+	{ID: 17, Name: "终点", Campus: 2},
+	{ID: 18, Name: "起点", Campus: 3},
+	{ID: 19, Name: "杨家山", Campus: 3},
+	{ID: 20, Name: "丁家桥", Campus: 3},
+	{ID: 21, Name: "兆丰公园", Campus: 3},
+	{ID: 22, Name: "终点", Campus: 3},
+}
+
+// 默认路线依次经过的地点，下标即点位编号
+var defaultCheckpoints = map[uint8][]uint{
+	1: {1, 2, 3, 4, 5, 6, 7, 8},
+	2: {9, 10, 11, 15, 16, 17},
+	3: {9, 10, 12, 13, 14, 15, 16, 17},
+	4: {18, 22},
+	5: {18, 19, 20, 21, 22},
+}
+
 func ConstantInit() {
-	if !global.Config.IsSet("number.ZH") || !global.Config.IsSet("number.PF_Half") || !global.Config.IsSet("number.PF_All") || !global.Config.IsSet("number.MGS_Half") || !global.Config.IsSet("number.MGS_All") {
-		log.Fatal("点位数量未设置")
+	var count int64
+	if err := global.DB.Model(&model.Route{}).Count(&count).Error; err != nil {
+		log.Fatal("路线读取错误: ", err)
 	}
-	constant.PointMap[1] = uint8(global.Config.GetInt("number.ZH"))
-	constant.PointMap[2] = uint8(global.Config.GetInt("number.PF_Half"))
-	constant.PointMap[3] = uint8(global.Config.GetInt("number.PF_All"))
-	constant.PointMap[4] = uint8(global.Config.GetInt("number.MGS_Half"))
-	constant.PointMap[5] = uint8(global.Config.GetInt("number.MGS_All"))
+
+	// 首次启动时写入默认路线、地点和点位
+	if count == 0 {
+		err := global.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&defaultRoutes).Error; err != nil {
+				return err
+			}
+			if err := tx.Create(&defaultSites).Error; err != nil {
+				return err
+			}
+
+			siteNames := make(map[uint]string)
+			for _, site := range defaultSites {
+				siteNames[site.ID] = site.Name
+			}
+			var checkpoints []model.Checkpoint
+			for _, route := range defaultRoutes {
+				for point, siteID := range defaultCheckpoints[route.ID] {
+					checkpoints = append(checkpoints, model.Checkpoint{
+						RouteID: route.ID,
+						Point:   int8(point),
+						SiteID:  siteID,
+						Name:    siteNames[siteID],
+					})
+				}
+			}
+			return tx.Create(&checkpoints).Error
+		})
+		if err != nil {
+			log.Fatal("默认路线写入错误: ", err)
+		}
+	}
+
+	if err := backfillSites(); err != nil {
+		log.Fatal("点位地点补全错误: ", err)
+	}
+
+	if err := routeService.Load(); err != nil {
+		log.Fatal("路线加载错误: ", err)
+	}
+}
+
+// backfillSites 为没有地点的点位补上地点，这些点位来自引入地点之前写入的数据库
+// 与默认路线一致的点位使用默认地点，其余点位按名称各自新建地点
+func backfillSites() error {
+	var checkpoints []model.Checkpoint
+	if err := global.DB.Where("site_id = ?", 0).Find(&checkpoints).Error; err != nil {
+		return err
+	}
+	if len(checkpoints) == 0 {
+		return nil
+	}
+
+	return global.DB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&model.Site{}).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			sites := append([]model.Site(nil), defaultSites...)
+			if err := tx.Create(&sites).Error; err != nil {
+				return err
+			}
+		}
+
+		var sites []model.Site
+		if err := tx.Find(&sites).Error; err != nil {
+			return err
+		}
+		existing := make(map[uint]model.Site, len(sites))
+		for _, site := range sites {
+			existing[site.ID] = site
+		}
+		defaults := make(map[uint]model.Site, len(defaultSites))
+		for _, site := range defaultSites {
+			defaults[site.ID] = site
+		}
+		var routes []model.Route
+		if err := tx.Find(&routes).Error; err != nil {
+			return err
+		}
+		campus := make(map[uint8]uint8, len(routes))
+		for _, route := range routes {
+			campus[route.ID] = route.Campus
+		}
+
+		for _, checkpoint := range checkpoints {
+			var siteID uint
+			if ids := defaultCheckpoints[checkpoint.RouteID]; checkpoint.Point >= 0 && int(checkpoint.Point) < len(ids) {
+				// 地点表中对应的地点仍是默认地点时才使用
+				id := ids[checkpoint.Point]
+				if site, ok := existing[id]; ok && site.Name == defaults[id].Name && site.Campus == defaults[id].Campus {
+					siteID = id
+				}
+			}
+			if siteID == 0 {
+				site := model.Site{Name: checkpoint.Name, Campus: campus[checkpoint.RouteID]}
+				if err := tx.Create(&site).Error; err != nil {
+					return err
+				}
+				siteID = site.ID
+			}
+			if err := tx.Model(&model.Checkpoint{}).Where("id = ?", checkpoint.ID).Update("site_id", siteID).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
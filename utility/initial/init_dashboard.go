@@ -0,0 +1,13 @@
+package initial
+
+import (
+	"log"
+	"walk-server/service/dashboardService"
+)
+
+// DashboardInit 启动时根据数据库重新统计大屏看板计数
+func DashboardInit() {
+	if err := dashboardService.Rebuild(); err != nil {
+		log.Fatal("看板计数初始化错误: ", err)
+	}
+}
@@ -0,0 +1,19 @@
+package initial
+
+import (
+	"log"
+	"time"
+	"walk-server/utility"
+)
+
+// FileInit 定时删除超过保留时间的生成文件
+func FileInit() {
+	go func() {
+		for {
+			if err := utility.CleanFiles(); err != nil {
+				log.Println("生成文件清理失败: ", err)
+			}
+			time.Sleep(time.Hour)
+		}
+	}()
+}
@@ -0,0 +1,45 @@
+package initial
+
+import (
+	"log"
+	"strconv"
+	"walk-server/global"
+	"walk-server/service/identityService"
+	"walk-server/utility"
+)
+
+// IdentityInit 按配置为各类人员设置报名时的身份验证方式
+func IdentityInit() {
+	rosterPath := global.Config.GetString("identity.roster")
+	created := map[string]identityService.IdentityProvider{}
+	provider := func(name string) identityService.IdentityProvider {
+		if p, ok := created[name]; ok {
+			return p
+		}
+		if name == "local" && !utility.IsDebugMode() {
+			log.Fatal("身份验证初始化错误: local 只能在调试模式下使用")
+		}
+		p, err := identityService.NewProvider(name, rosterPath)
+		if err != nil {
+			log.Fatal("身份验证初始化错误: ", err)
+		}
+		created[name] = p
+		return p
+	}
+
+	// 没有配置时学生和教职工都使用统一验证
+	providers := global.Config.GetStringMapString("identity.providers")
+	if len(providers) == 0 {
+		providers = map[string]string{"1": "campus", "2": "campus"}
+	}
+	for key, name := range providers {
+		personType, err := strconv.ParseUint(key, 10, 8)
+		if err != nil {
+			log.Fatal("身份验证初始化错误: 人员类型 ", key, " 无效")
+		}
+		identityService.Register(uint8(personType), provider(name))
+	}
+	if name := global.Config.GetString("identity.fallback"); name != "" {
+		identityService.SetFallback(provider(name))
+	}
+}
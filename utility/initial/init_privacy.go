@@ -0,0 +1,53 @@
+package initial
+
+import (
+	"log"
+	"walk-server/global"
+	"walk-server/privacy"
+	"walk-server/service/userService"
+	"walk-server/utility"
+)
+
+// PrivacyInit 初始化个人信息密钥，并加密迁移前明文保存的身份证号
+func PrivacyInit() {
+	key := global.Config.GetString("privacy.key")
+	if key == "" {
+		log.Fatal("个人信息密钥未配置: privacy.key")
+	}
+	if err := privacy.Init(key); err != nil {
+		log.Fatal("个人信息密钥初始化错误: ", err)
+	}
+
+	// 没有盲索引的是迁移前的数据，绕过模型直接读写原始列，已注销的用户没有身份证号
+	var rows []struct {
+		OpenId   string
+		Identity string
+	}
+	err := global.DB.Table("people").
+		Select("open_id", "identity").
+		Where("identity_index IS NULL AND open_id NOT LIKE ?", userService.DeletedOpenIDPrefix+"%").
+		Scan(&rows).Error
+	if err != nil {
+		log.Fatal("身份证号迁移错误: ", err)
+	}
+	for _, row := range rows {
+		identity, err := privacy.Decrypt(row.Identity)
+		if err != nil {
+			log.Fatal("身份证号迁移错误: ", err)
+		}
+		identity = utility.NormalizeIdentity(identity)
+		encrypted, err := privacy.Encrypt(identity)
+		if err != nil {
+			log.Fatal("身份证号迁移错误: ", err)
+		}
+		err = global.DB.Table("people").
+			Where("open_id = ?", row.OpenId).
+			UpdateColumns(map[string]interface{}{
+				"identity":       encrypted,
+				"identity_index": privacy.BlindIndex(identity),
+			}).Error
+		if err != nil {
+			log.Fatal("身份证号迁移错误: ", err)
+		}
+	}
+}
@@ -0,0 +1,13 @@
+package initial
+
+import (
+	"log"
+	"walk-server/service/quotaService"
+)
+
+// QuotaInit 从数据库加载每天各路线的报名名额，数据库中没有的名额从配置文件 teamUpperLimit 导入
+func QuotaInit() {
+	if err := quotaService.Init(); err != nil {
+		log.Fatal("报名名额初始化错误: ", err)
+	}
+}
@@ -0,0 +1,29 @@
+package initial
+
+import (
+	"log"
+	"time"
+	"walk-server/global"
+	"walk-server/service/reconcileService"
+)
+
+// ReconcileInit 定时比对 Redis 和 MySQL 中的提交状态并修复偏差
+// 比对间隔读取配置 reconcile.interval（分钟），默认 10 分钟
+func ReconcileInit() {
+	interval := global.Config.GetInt("reconcile.interval")
+	if interval <= 0 {
+		interval = 10
+	}
+	go func() {
+		for {
+			report, err := reconcileService.Run(true)
+			if err != nil {
+				log.Println("提交状态比对失败: ", err)
+			} else if !report.Consistent {
+				log.Printf("提交状态比对发现不一致: 提交状态 %d, 失效提交 %d, 失效候补 %d, 名额 %d, 剩余名额 %d\n",
+					len(report.Submit), len(report.StaleTeams), len(report.StaleWaits), len(report.Quotas), len(report.Counters))
+			}
+			time.Sleep(time.Duration(interval) * time.Minute)
+		}
+	}()
+}
@@ -3,8 +3,8 @@ package initial
 import (
 	"fmt"
 	"os"
-	"strconv"
 	"walk-server/global"
+	"walk-server/service/teamService"
 
 	"github.com/redis/go-redis/v9"
 )
@@ -30,14 +30,21 @@ func RedisInit() {
 		os.Exit(-1)
 	}
 
-	// 初始化每天各路线报名上限
-	for i := 0; i <= 2; i++ { // 枚举天数
-		for j := 1; j <= 5; j++ { // 枚举路线编号
-			key := strconv.Itoa(i*10 + j)
-			value := global.Config.GetInt("teamUpperLimit" + "." + strconv.Itoa(i) + "." + strconv.Itoa(j))
-			if _, err := global.Rdb.Get(global.Rctx, key).Result(); err == redis.Nil {
-				global.Rdb.Set(global.Rctx, key, value, 0)
-			}
+	// 一次性迁移旧版本只保存在 Redis 中的提交
+	if global.Config.GetBool("reconcile.migrateLegacy") {
+		migrated, err := teamService.MigrateLegacySubmitted()
+		if err != nil {
+			fmt.Println("提交状态迁移失败")
+			fmt.Println(err)
+			os.Exit(-1)
 		}
+		fmt.Println("已迁移", migrated, "支只保存在 Redis 中的已提交队伍，请关闭 reconcile.migrateLegacy")
+	}
+
+	// Redis 只缓存提交状态，根据数据库重建已提交的队伍
+	if err := teamService.RebuildSubmitted(); err != nil {
+		fmt.Println("提交状态恢复失败")
+		fmt.Println(err)
+		os.Exit(-1)
 	}
 }
@@ -1,28 +1,103 @@
 package utility
 
 import (
-	"strings"
+	"errors"
 	"time"
 	"walk-server/global"
+	"walk-server/model"
 
-	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// 参与者和管理员的 token 使用不同的 audience，中间件只接受对应类型的 token
+const (
+	KindUser   = "user"   // 参与者
+	KindAdmin  = "admin"  // 管理员
+	KindStream = "stream" // 管理员订阅大屏推送
+
+	AudienceUser   = "walk-user"
+	AudienceAdmin  = "walk-admin"
+	AudienceStream = "walk-stream"
+
+	jwtIssuer = "JHWL"
+
+	// streamTokenLifetime 大屏推送 token 只用于建立连接，有效期很短
+	streamTokenLifetime = time.Minute
+)
+
+// ErrTokenKind token 的类型与接口不符
+var ErrTokenKind = errors.New("token kind mismatch")
+
 // JwtData 一些结构体的定义
 type JwtData struct {
-	OpenID string `json:"open_id"`
+	Kind    string `json:"kind"`               // token 类型，user 或 admin
+	OpenID  string `json:"open_id,omitempty"`  // 参与者加密后的 OpenID
+	AdminID uint   `json:"admin_id,omitempty"` // 管理员ID
+	Role    uint8  `json:"role,omitempty"`     // 签发时管理员的角色
+	Route   uint8  `json:"route,omitempty"`    // 签发时管理员负责的路线
+	Point   int8   `json:"point,omitempty"`    // 签发时管理员所在的点位
+	Session string `json:"sid,omitempty"`      // 所属的登录会话，会话撤销后 token 失效
 	jwt.RegisteredClaims
 }
 
+// NewUserClaims 参与者 token 的数据
+func NewUserClaims(openID string) *JwtData {
+	return &JwtData{Kind: KindUser, OpenID: openID}
+}
+
+// NewAdminClaims 管理员 token 的数据
+func NewAdminClaims(admin *model.Admin) *JwtData {
+	return &JwtData{
+		Kind:    KindAdmin,
+		AdminID: admin.ID,
+		Role:    admin.Role,
+		Route:   admin.Route,
+		Point:   admin.Point,
+	}
+}
+
+// AccessTokenLifetime access token 的有效期，读取配置 jwt.access（分钟），默认 30 分钟
+// 过期后通过 refresh token 换取新的 access token
+func AccessTokenLifetime() time.Duration {
+	minutes := global.Config.GetInt("jwt.access")
+	if minutes <= 0 {
+		minutes = 30
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// audience 根据 token 类型返回对应的 audience
+func audience(kind string) string {
+	switch kind {
+	case KindAdmin:
+		return AudienceAdmin
+	case KindStream:
+		return AudienceStream
+	}
+	return AudienceUser
+}
+
 // GenerateStandardJwt 根据数据生成带有 standard claims 的 jwt token
 func GenerateStandardJwt(jwtData *JwtData) (string, error) {
+	return signToken(jwtData, AccessTokenLifetime())
+}
+
+// GenerateStreamToken 根据管理员的 token 签发大屏推送使用的短期 token，属于同一个会话
+// 浏览器的 EventSource 不能设置请求头，这个 token 放在查询参数中
+func GenerateStreamToken(admin *JwtData) (string, error) {
+	claims := *admin
+	claims.Kind = KindStream
+	return signToken(&claims, streamTokenLifetime)
+}
+
+func signToken(jwtData *JwtData, lifetime time.Duration) (string, error) {
 	claims := jwtData
 	claims.RegisteredClaims = jwt.RegisteredClaims{
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(24*7) * time.Hour)), // 过期时间（7天）
-		IssuedAt:  jwt.NewNumericDate(time.Now()),                                      // 签发时间
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(lifetime)), // 过期时间
+		IssuedAt:  jwt.NewNumericDate(time.Now()),               // 签发时间
 		NotBefore: jwt.NewNumericDate(time.Now()),
-		Issuer:    "JHWL", // 签发人
+		Issuer:    jwtIssuer,                                // 签发人
+		Audience:  jwt.ClaimStrings{audience(jwtData.Kind)}, // 接收方
 	}
 
 	tokenClaims := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -32,12 +107,51 @@ func GenerateStandardJwt(jwtData *JwtData) (string, error) {
 }
 
 // ParseToken 根据传入的token值获取到Claims对象信息，（进而获取其中的用户名和密码）
+// 只接受带有类型的 token，不区分参与者和管理员，需要区分时使用 ParseUserToken 和 ParseAdminToken
 func ParseToken(token string) (*JwtData, error) {
+	claims, err := parseToken(token)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Kind != KindUser && claims.Kind != KindAdmin {
+		return nil, ErrTokenKind
+	}
+	return claims, nil
+}
+
+// ParseUserToken 解析参与者的 token，管理员的 token 会被拒绝
+func ParseUserToken(token string) (*JwtData, error) {
+	return parseTokenOf(token, KindUser)
+}
+
+// ParseAdminToken 解析管理员的 token，参与者的 token 会被拒绝
+func ParseAdminToken(token string) (*JwtData, error) {
+	return parseTokenOf(token, KindAdmin)
+}
+
+// ParseStreamToken 解析大屏推送的短期 token
+func ParseStreamToken(token string) (*JwtData, error) {
+	return parseTokenOf(token, KindStream)
+}
+
+func parseTokenOf(token string, kind string) (*JwtData, error) {
+	claims, err := parseToken(token, jwt.WithAudience(audience(kind)))
+	if err != nil {
+		return nil, err
+	}
+	if claims.Kind != kind {
+		return nil, ErrTokenKind
+	}
+	return claims, nil
+}
+
+func parseToken(token string, options ...jwt.ParserOption) (*JwtData, error) {
 	jwtSecret := []byte(global.Config.GetString("server.JWTSecret"))
+	options = append(options, jwt.WithIssuer(jwtIssuer), jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
 	//用于解析鉴权的声明，方法内部主要是具体的解码和校验的过程，最终返回*Token
 	tokenClaims, err := jwt.ParseWithClaims(token, &JwtData{}, func(token *jwt.Token) (interface{}, error) {
 		return jwtSecret, nil
-	})
+	}, options...)
 
 	if tokenClaims != nil {
 		// 从tokenClaims中获取到Claims对象，并使用断言，将该对象转换为我们自己定义的Claims
@@ -48,24 +162,3 @@ func ParseToken(token string) (*JwtData, error) {
 	}
 	return nil, err
 }
-
-// UrlToken 用来生成能在 url 中传输的
-func UrlToken(jwtData *JwtData) (string, error) {
-	jwtToken, err := GenerateStandardJwt(jwtData)
-
-	// 将 jwtToken 中的 . 符号替换成能在 http 中传输的 !
-	jwtToken = strings.Replace(jwtToken, ".", "!", 2)
-
-	return jwtToken, err
-}
-
-// GetJwtData 从控制器上下文中获取 jwt 数据
-func GetJwtData(context *gin.Context) *JwtData {
-	// 获取 jwt 数据
-	jwtToken := context.GetHeader("Authorization")[7:]
-	jwtData, err := ParseToken(jwtToken)
-	if err != nil {
-		return nil
-	}
-	return jwtData
-}
@@ -1,8 +1,16 @@
 package utility
 
-import "github.com/gin-gonic/gin"
+import (
+	"log"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+)
 
 func ResponseData(context *gin.Context, statusCode int, msg string, data gin.H) {
+	// 记录响应结果，供管理端审计日志使用
+	context.Set("response_code", statusCode)
+	context.Set("response_msg", msg)
 	context.JSON(statusCode, gin.H{
 		"code": statusCode,
 		"msg":  msg,
@@ -19,3 +27,21 @@ func ResponseSuccess(context *gin.Context, data gin.H) {
 func ResponseError(context *gin.Context, error string) {
 	ResponseData(context, -1, error, nil)
 }
+
+// ResponseExcel 生成 Excel 文件并作为附件直接返回
+func ResponseExcel(context *gin.Context, fileName string, data File) {
+	f, err := newExcelFile(data)
+	if err != nil {
+		ResponseError(context, "生成文件失败")
+		return
+	}
+	defer f.Close()
+
+	context.Set("response_code", 200)
+	context.Set("response_msg", "ok")
+	context.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	context.Header("Content-Disposition", "attachment; filename*=UTF-8''"+url.PathEscape(fileName))
+	if _, err := f.WriteTo(context.Writer); err != nil {
+		log.Printf("写入 Excel 文件失败: %v", err)
+	}
+}
@@ -0,0 +1,50 @@
+package utility
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	identityRegexp = regexp.MustCompile(`^\d{17}[\dX]$`)
+	phoneRegexp    = regexp.MustCompile(`^1[3-9]\d{9}$`)
+)
+
+// 身份证号前 17 位的加权因子和校验码
+var (
+	identityWeights = []int{7, 9, 10, 5, 8, 4, 2, 1, 6, 3, 7, 9, 10, 5, 8, 4, 2}
+	identityChecks  = "10X98765432"
+)
+
+// IsValidIdentity 校验 18 位身份证号的格式和校验码，末位 x 需要先转为大写
+func IsValidIdentity(id string) bool {
+	if !identityRegexp.MatchString(id) {
+		return false
+	}
+	sum := 0
+	for i, weight := range identityWeights {
+		sum += int(id[i]-'0') * weight
+	}
+	return id[17] == identityChecks[sum%11]
+}
+
+// NormalizeIdentity 去掉身份证号两端空白并把末位 x 转为大写
+func NormalizeIdentity(id string) string {
+	return strings.ToUpper(strings.TrimSpace(id))
+}
+
+// GenderFromIdentity 根据身份证号第 17 位获取性别，1 男 2 女，长度不足时返回 0
+func GenderFromIdentity(id string) int8 {
+	if len(id) < 17 {
+		return 0
+	}
+	if (id[16]-'0')%2 == 1 {
+		return 1
+	}
+	return 2
+}
+
+// IsValidPhone 校验中国大陆手机号
+func IsValidPhone(tel string) bool {
+	return phoneRegexp.MatchString(tel)
+}
@@ -0,0 +1,73 @@
+package utility
+
+import "testing"
+
+func TestIsValidIdentity(t *testing.T) {
+	tests := []struct {
+		name string
+		id   string
+		want bool
+	}{
+		{"valid", "440304199001010011", true},
+		{"valid with X", "11010519491231002X", true},
+		{"bad checksum", "440304199001010012", false},
+		{"bad checksum X", "33010619980512003X", false},
+		{"lowercase x", "11010519491231002x", false},
+		{"lowercase x normalized", NormalizeIdentity(" 11010519491231002x "), true},
+		{"short", "4403041990", false},
+		{"empty", "", false},
+		{"too long", "4403041990010100111", false},
+		{"letters", "44030419900101A011", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsValidIdentity(tt.id); got != tt.want {
+				t.Fatalf("IsValidIdentity(%q) = %v, want %v", tt.id, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenderFromIdentity(t *testing.T) {
+	tests := []struct {
+		name string
+		id   string
+		want int8
+	}{
+		{"male", "440304199001010011", 1},
+		{"female", "11010519491231002X", 2},
+		{"male odd digit", "330106199805120036", 1},
+		{"short", "4403041990", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GenderFromIdentity(tt.id); got != tt.want {
+				t.Fatalf("GenderFromIdentity(%q) = %d, want %d", tt.id, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsValidPhone(t *testing.T) {
+	tests := []struct {
+		name string
+		tel  string
+		want bool
+	}{
+		{"valid", "13812345678", true},
+		{"valid 19x", "19912345678", true},
+		{"second digit 2", "12812345678", false},
+		{"short", "1381234567", false},
+		{"long", "138123456789", false},
+		{"country code", "+8613812345678", false},
+		{"letters", "1381234567a", false},
+		{"empty", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsValidPhone(tt.tel); got != tt.want {
+				t.Fatalf("IsValidPhone(%q) = %v, want %v", tt.tel, got, tt.want)
+			}
+		})
+	}
+}